@@ -0,0 +1,175 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// awsEventsPrefix is the prefix of the topics on which AWS IoT publishes fleet events. Presence events are covered by
+// PresenceConnectedTopic and PresenceDisconnectedTopic. Most event types must be enabled in the account's event
+// configuration before they're published. See https://docs.aws.amazon.com/iot/latest/developerguide/iot-events.html.
+const awsEventsPrefix = "$aws/events/"
+
+// ThingEventTopic returns the topic on which AWS IoT publishes registry events for the named thing. event is
+// "created", "updated", or "deleted". Pass "+" for either to get a filter that matches all of them.
+func ThingEventTopic(thingName, event string) string {
+	return fmt.Sprintf("%sthing/%s/%s", awsEventsPrefix, thingName, event)
+}
+
+// ThingGroupEventTopic returns the topic on which AWS IoT publishes registry events for the named thing group. event
+// is "created", "updated", or "deleted". Pass "+" for either to get a filter that matches all of them.
+func ThingGroupEventTopic(groupName, event string) string {
+	return fmt.Sprintf("%sthingGroup/%s/%s", awsEventsPrefix, groupName, event)
+}
+
+// JobEventTopic returns the topic on which AWS IoT publishes events for the job with the given ID. event is e.g.
+// "completed", "canceled", or "deleted". Pass "+" for either to get a filter that matches all of them.
+func JobEventTopic(jobID, event string) string {
+	return fmt.Sprintf("%sjob/%s/%s", awsEventsPrefix, jobID, event)
+}
+
+// JobExecutionEventTopic returns the topic on which AWS IoT publishes events for executions of the job with the
+// given ID. event is e.g. "succeeded", "failed", "rejected", "canceled", "removed", or "timed_out". Pass "+" for
+// either to get a filter that matches all of them.
+func JobExecutionEventTopic(jobID, event string) string {
+	return fmt.Sprintf("%sjobExecution/%s/%s", awsEventsPrefix, jobID, event)
+}
+
+// CertificateRegisteredTopic returns the topic on which AWS IoT publishes an event when a device cert signed by the
+// CA cert with the given ID is automatically registered, i.e. on a device's first connection with just-in-time
+// registration. Pass "+" to get a filter that matches all CA certs.
+func CertificateRegisteredTopic(caCertificateID string) string {
+	return fmt.Sprintf("%scertificates/registered/%s", awsEventsPrefix, caCertificateID)
+}
+
+// FleetEvent holds the fields common to registry and job events.
+type FleetEvent struct {
+	EventType string `json:"eventType"`
+	EventID   string `json:"eventId"`
+	// Timestamp is the time of the event in milliseconds since the Unix epoch. Use Time to get it as a time.Time.
+	Timestamp int64 `json:"timestamp"`
+	// Operation is e.g. "CREATED", "UPDATED", or "DELETED" for registry events.
+	Operation string `json:"operation"`
+}
+
+// Time returns the time of the event.
+func (e *FleetEvent) Time() time.Time {
+	return time.UnixMilli(e.Timestamp)
+}
+
+// ThingEvent is the payload of a thing registry event.
+type ThingEvent struct {
+	FleetEvent
+	AccountID     string            `json:"accountId"`
+	ThingID       string            `json:"thingId"`
+	ThingName     string            `json:"thingName"`
+	VersionNumber int64             `json:"versionNumber"`
+	ThingTypeName string            `json:"thingTypeName"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+// ThingGroupEvent is the payload of a thing group registry event.
+type ThingGroupEvent struct {
+	FleetEvent
+	AccountID      string            `json:"accountId"`
+	ThingGroupID   string            `json:"thingGroupId"`
+	ThingGroupName string            `json:"thingGroupName"`
+	VersionNumber  int64             `json:"versionNumber"`
+	ParentGroup    string            `json:"parentGroupName"`
+	Description    string            `json:"description"`
+	Attributes     map[string]string `json:"attributes"`
+}
+
+// JobEvent is the payload of a job event.
+type JobEvent struct {
+	FleetEvent
+	JobID           string   `json:"jobId"`
+	Status          string   `json:"status"`
+	TargetSelection string   `json:"targetSelection"`
+	Targets         []string `json:"targets"`
+	Description     string   `json:"description"`
+}
+
+// JobExecutionEvent is the payload of a job execution event.
+type JobExecutionEvent struct {
+	FleetEvent
+	JobID         string            `json:"jobId"`
+	ThingARN      string            `json:"thingArn"`
+	Status        string            `json:"status"`
+	StatusDetails map[string]string `json:"statusDetails"`
+}
+
+// CertificateRegisteredEvent is the payload of a certificate registration event.
+type CertificateRegisteredEvent struct {
+	CertificateID     string `json:"certificateId"`
+	CACertificateID   string `json:"caCertificateId"`
+	CertificateStatus string `json:"certificateStatus"`
+	AccountID         string `json:"awsAccountId"`
+	// Timestamp is the time of the event in milliseconds since the Unix epoch.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// ParseThingEvent parses the payload of a thing registry event.
+func ParseThingEvent(payload []byte) (*ThingEvent, error) {
+	var e ThingEvent
+	if err := parseFleetEvent(payload, &e, &e.FleetEvent, "THING_EVENT"); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ParseThingGroupEvent parses the payload of a thing group registry event.
+func ParseThingGroupEvent(payload []byte) (*ThingGroupEvent, error) {
+	var e ThingGroupEvent
+	if err := parseFleetEvent(payload, &e, &e.FleetEvent, "THING_GROUP_EVENT"); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ParseJobEvent parses the payload of a job event.
+func ParseJobEvent(payload []byte) (*JobEvent, error) {
+	var e JobEvent
+	if err := parseFleetEvent(payload, &e, &e.FleetEvent, "JOB"); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ParseJobExecutionEvent parses the payload of a job execution event.
+func ParseJobExecutionEvent(payload []byte) (*JobExecutionEvent, error) {
+	var e JobExecutionEvent
+	if err := parseFleetEvent(payload, &e, &e.FleetEvent, "JOB_EXECUTION"); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ParseCertificateRegisteredEvent parses the payload of a certificate registration event.
+func ParseCertificateRegisteredEvent(payload []byte) (*CertificateRegisteredEvent, error) {
+	var e CertificateRegisteredEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse certificate registered event: %w", err)
+	}
+
+	if e.CertificateID == "" {
+		return nil, fmt.Errorf("awsiotcore: certificate registered event has no certificate ID")
+	}
+
+	return &e, nil
+}
+
+// parseFleetEvent unmarshals payload into v, whose embedded FleetEvent is common, and checks that the event is of
+// the given type.
+func parseFleetEvent(payload []byte, v interface{}, common *FleetEvent, eventType string) error {
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("awsiotcore: failed to parse %s event: %w", eventType, err)
+	}
+
+	if common.EventType != eventType {
+		return fmt.Errorf("awsiotcore: got event type %q, want %q", common.EventType, eventType)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package awsiotcore
+
+import (
+	"testing"
+)
+
+func TestFleetEventTopics(t *testing.T) {
+	cases := []struct {
+		got  string
+		want string
+	}{
+		{ThingEventTopic("foo", "+"), "$aws/events/thing/foo/+"},
+		{ThingGroupEventTopic("fleetA", "updated"), "$aws/events/thingGroup/fleetA/updated"},
+		{JobEventTopic("ota-1", "completed"), "$aws/events/job/ota-1/completed"},
+		{JobExecutionEventTopic("+", "failed"), "$aws/events/jobExecution/+/failed"},
+		{CertificateRegisteredTopic("abc"), "$aws/events/certificates/registered/abc"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("got %q, want %q", c.got, c.want)
+		}
+	}
+}
+
+func TestParseThingEvent(t *testing.T) {
+	payload := []byte(`{
+		"eventType": "THING_EVENT",
+		"eventId": "f5ae9b94-8b8e-4d8e-8c8f-b3266dd89853",
+		"timestamp": 1234567890123,
+		"operation": "CREATED",
+		"accountId": "123456789012",
+		"thingId": "b604f69c-aa9a-4d4a-829e-c480e958a0b5",
+		"thingName": "foo",
+		"versionNumber": 1,
+		"thingTypeName": null,
+		"attributes": {"room": "kitchen"}
+	}`)
+
+	e, err := ParseThingEvent(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.ThingName != "foo" || e.Operation != "CREATED" || e.Attributes["room"] != "kitchen" || e.Time().UnixMilli() != 1234567890123 {
+		t.Errorf("got %+v", e)
+	}
+
+	if _, err := ParseJobEvent(payload); err == nil {
+		t.Errorf("got nil error parsing a thing event as a job event, want non-nil")
+	}
+}
+
+func TestParseJobExecutionEvent(t *testing.T) {
+	payload := []byte(`{
+		"eventType": "JOB_EXECUTION",
+		"eventId": "cca89fa5-8a7f-4ced-8c87-7c3c3b1bb7a6",
+		"timestamp": 1234567890,
+		"operation": "succeeded",
+		"jobId": "ota-1",
+		"thingArn": "arn:aws:iot:us-east-1:123456789012:thing/foo",
+		"status": "SUCCEEDED",
+		"statusDetails": {"percent": "100"}
+	}`)
+
+	e, err := ParseJobExecutionEvent(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.JobID != "ota-1" || e.Status != "SUCCEEDED" || e.StatusDetails["percent"] != "100" {
+		t.Errorf("got %+v", e)
+	}
+}
+
+func TestParseCertificateRegisteredEvent(t *testing.T) {
+	e, err := ParseCertificateRegisteredEvent([]byte(`{"certificateId": "abc", "caCertificateId": "def", "certificateStatus": "PENDING_ACTIVATION"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.CertificateID != "abc" || e.CACertificateID != "def" || e.CertificateStatus != "PENDING_ACTIVATION" {
+		t.Errorf("got %+v", e)
+	}
+
+	if _, err := ParseCertificateRegisteredEvent([]byte(`{}`)); err == nil {
+		t.Errorf("got nil error for event with no certificate ID, want non-nil")
+	}
+}
@@ -3,33 +3,21 @@
 package awsiotcore
 
 import (
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/pem"
+	"encoding/hex"
 	"fmt"
-	"io/ioutil"
+	"net/url"
 	"os"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
 // DeviceIDFromCert gets the Common Name from an X.509 cert, which for the purposes of this package is considered to be the device ID.
 func DeviceIDFromCert(certPath string) (string, error) {
-	certBytes, err := ioutil.ReadFile(certPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("awsiotcore: cert file does not exist: %v", certPath)
-		}
-
-		return "", fmt.Errorf("awsiotcore: failed to read cert: %v", err)
-	}
-
-	block, _ := pem.Decode(certBytes)
-	if block == nil || block.Type != "CERTIFICATE" {
-		return "", fmt.Errorf("awsiotcore: failed to decode PEM certificate")
-	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
+	cert, err := readCert(certPath)
 	if err != nil {
 		return "", err
 	}
@@ -46,6 +34,28 @@ type Device struct {
 	CACerts     string `json:"ca_certs_path"`
 	CertPath    string `json:"cert_path"`
 	PrivKeyPath string `json:"priv_key_path"`
+	// LegacyEndpoint should be set if Endpoint is intentionally a legacy (non-ATS) AWS IoT endpoint. It silences the
+	// warning NewClient logs for such endpoints.
+	LegacyEndpoint bool `json:"legacy_endpoint"`
+	// CustomAuthorizer is the name of the AWS IoT custom authorizer with which the device authenticates, if any. If
+	// it's set then CertPath and PrivKeyPath may be left empty, in which case the client presents no certificate.
+	// The authorizer's token is sent as the MQTT password; set it with WithPassword.
+	// See https://docs.aws.amazon.com/iot/latest/developerguide/custom-authentication.html.
+	CustomAuthorizer string `json:"custom_authorizer"`
+	// BridgeTopicPrefix is set when the device connects to a local broker that bridges to AWS IoT and adds a prefix
+	// to topics on the local side. It's prepended to every topic the Device's methods return, e.g. TelemetryTopic and
+	// ShadowTopic, including TelemetryTopicOverride. Use AWSTopic to map such a topic to its AWS-side equivalent.
+	BridgeTopicPrefix string `json:"bridge_topic_prefix"`
+	// TopicQoS maps topic filters, which may contain wildcards, to the QoS at which the Device's methods, e.g.
+	// PublishTelemetry, HandleCommands' acks, ReportJobProgress, and shadow and stream requests, publish to matching
+	// topics. Filters are matched against topics as published, i.e. with BridgeTopicPrefix. An exact match wins;
+	// otherwise the longest matching filter does. Topics that match no filter are published at the method's default
+	// QoS.
+	TopicQoS map[string]byte `json:"topic_qos"`
+
+	// clock is the Clock used by the Device's time-dependent methods. It's set with WithClock; nil means the system
+	// clock.
+	clock Clock
 }
 
 // NewClient creates a github.com/eclipse/paho.mqtt.golang Client that may be used to connect to the device's MQTT broker using TLS.
@@ -74,6 +84,39 @@ type Device struct {
 //
 // For more information about connecting to AWS IoT MQTT brokers see https://docs.aws.amazon.com/iot/latest/developerguide/iot-connect-devices.html.
 func (d *Device) NewClient(options ...func(*Device, *mqtt.ClientOptions) error) (mqtt.Client, error) {
+	client, _, err := d.NewClientWithOptions(options...)
+	return client, err
+}
+
+// NewClientWithOptions is like NewClient but also returns the ClientOptions used to create the client, after all
+// options have been applied. This is useful for logging the effective configuration and for asserting on it in
+// tests. The returned ClientOptions are a copy with their own TLS config and broker URLs, so changing them has no
+// effect on the client. paho's own copy of the options is shallow, so without this the TLS config and broker URLs
+// would be shared with the live client.
+func (d *Device) NewClientWithOptions(options ...func(*Device, *mqtt.ClientOptions) error) (mqtt.Client, *mqtt.ClientOptions, error) {
+	opts, err := d.clientOptions(options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := mqtt.NewClient(opts)
+	ret := *opts
+	ret.TLSConfig = opts.TLSConfig.Clone()
+	ret.Servers = make([]*url.URL, len(opts.Servers))
+	for i, u := range opts.Servers {
+		c := *u
+		ret.Servers[i] = &c
+	}
+	return client, &ret, nil
+}
+
+// clientOptions builds the ClientOptions for the device and applies the given options to them.
+func (d *Device) clientOptions(options ...func(*Device, *mqtt.ClientOptions) error) (*mqtt.ClientOptions, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	d.warnLegacyEndpoint()
+
 	// Load CA certs.
 	pemCerts, err := os.ReadFile(d.CACerts)
 	if err != nil {
@@ -84,22 +127,24 @@ func (d *Device) NewClient(options ...func(*Device, *mqtt.ClientOptions) error)
 		return nil, fmt.Errorf("awsiotcore: no certs were parsed from given CA certs")
 	}
 
-	// Import client certificate/key pair.
-	cert, err := tls.LoadX509KeyPair(d.CertPath, d.PrivKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("awsiotcore: failed to load x509 key pair: %w", err)
-	}
-
 	tlsConf := &tls.Config{
-		RootCAs:      certpool,
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		Certificates: []tls.Certificate{cert},
+		RootCAs:    certpool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
 		// AWS IoT requires devices to send the Server Name Indication (SNI) TLS extension, and its value must be the endpoint address.
 		// See https://docs.aws.amazon.com/iot/latest/developerguide/transport-security.html.
 		ServerName: d.Endpoint,
 		MinVersion: tls.VersionTLS12,
 	}
 
+	// Import client certificate/key pair. With a custom authorizer the device may not have one.
+	if d.CertPath != "" {
+		cert, err := loadX509KeyPair(d.CertPath, d.PrivKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("awsiotcore: failed to load x509 key pair: %w", err)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+	}
+
 	broker := d.Broker()
 
 	// See https://docs.aws.amazon.com/iot/latest/developerguide/transport-security.html
@@ -107,6 +152,9 @@ func (d *Device) NewClient(options ...func(*Device, *mqtt.ClientOptions) error)
 	opts.AddBroker(broker.URL())
 	opts.SetClientID(d.DeviceID)
 	opts.SetTLSConfig(tlsConf)
+	if d.CustomAuthorizer != "" {
+		opts.SetUsername(d.customAuthorizerUsername(""))
+	}
 
 	for _, option := range options {
 		if err := option(d, opts); err != nil {
@@ -114,13 +162,21 @@ func (d *Device) NewClient(options ...func(*Device, *mqtt.ClientOptions) error)
 		}
 	}
 
-	return mqtt.NewClient(opts), nil
+	return opts, nil
+}
+
+// customAuthorizerUsername returns the MQTT username that names the device's custom authorizer, with the given
+// username, if any, before the authorizer query.
+func (d *Device) customAuthorizerUsername(username string) string {
+	return username + "?x-amz-customauthorizer-name=" + url.QueryEscape(d.CustomAuthorizer)
 }
 
+// Broker returns the MQTT broker to which the device connects.
 func (d *Device) Broker() MQTTBroker {
 	return MQTTBroker{
-		Host: d.Endpoint,
-		Port: 8883,
+		Scheme: "ssl",
+		Host:   d.Endpoint,
+		Port:   8883,
 	}
 }
 
@@ -131,7 +187,80 @@ func (d *Device) ID() string {
 // TelemetryTopic returns the MQTT topic to which the device should publish telemetry events.
 func (d *Device) TelemetryTopic() string {
 	if d.TelemetryTopicOverride != "" {
-		return d.TelemetryTopicOverride
+		return d.bridgeTopic(d.TelemetryTopicOverride)
+	}
+	return d.bridgeTopic(fmt.Sprintf("things/%v/telemetry", d.DeviceID))
+}
+
+// waitToken waits up to timeout for t to complete and returns its error, if any.
+func waitToken(t mqtt.Token, timeout time.Duration) error {
+	if !t.WaitTimeout(timeout) {
+		return fmt.Errorf("awsiotcore: timed out after %v", timeout)
+	}
+	return t.Error()
+}
+
+// connectedPollInterval is how often WaitConnected checks whether the client is connected.
+const connectedPollInterval = 50 * time.Millisecond
+
+// WaitConnected blocks until client is connected or timeout elapses, whichever comes first, and returns an error in
+// the latter case. It's useful for gating startup on the first connection when the client connects in the background,
+// e.g. with WithConnectRetry, whose connect token doesn't complete until the connection succeeds. It checks the state
+// of the connection itself, so unlike IsConnected it doesn't count a client that's reconnecting as connected.
+func WaitConnected(client mqtt.Client, timeout time.Duration) error {
+	if client.IsConnectionOpen() {
+		return nil
 	}
-	return fmt.Sprintf("things/%v/telemetry", d.DeviceID)
+
+	ticker := time.NewTicker(connectedPollInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if client.IsConnectionOpen() {
+				return nil
+			}
+		case <-deadline.C:
+			return fmt.Errorf("awsiotcore: not connected after %v", timeout)
+		}
+	}
+}
+
+// ConnectAsync starts connecting client and returns a channel on which the result of the connection attempt is sent,
+// nil on success, once it's known. The channel is buffered and closed after the result is sent, so the result may
+// be received later or ignored without leaking a goroutine. It lets the program do other setup while connecting,
+// and select on the connection alongside other startup tasks. Note that with WithConnectRetry no result is sent
+// until a connection succeeds.
+func ConnectAsync(client mqtt.Client) <-chan error {
+	result := make(chan error, 1)
+	t := client.Connect()
+	go func() {
+		defer close(result)
+		<-t.Done()
+		if err := t.Error(); err != nil {
+			result <- fmt.Errorf("awsiotcore: failed to connect: %w", err)
+			return
+		}
+		result <- nil
+	}()
+	return result
+}
+
+// newClientToken returns a random string suitable for use as the clientToken in requests to AWS IoT services, which
+// echo it back in their responses so that they can be matched to requests.
+func newClientToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand.Read only fails if the OS's randomness source is broken.
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// ConfigTopic returns the MQTT topic on which the device's config snapshot is published as a retained message.
+func (d *Device) ConfigTopic() string {
+	return d.bridgeTopic(fmt.Sprintf("things/%v/config", d.DeviceID))
 }
@@ -1,9 +1,27 @@
 package awsiotcore
 
 import (
+	"errors"
 	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
+// testDevice returns a Device whose cert, key, and CA certs exist on disk. The device's own self-signed cert serves
+// as the CA cert.
+func testDevice(t *testing.T) *Device {
+	t.Helper()
+	tc := writeTestCert(t, "foo", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	return &Device{
+		Endpoint:    "abc123-ats.iot.us-west-2.amazonaws.com",
+		DeviceID:    "foo",
+		CACerts:     tc.certPath,
+		CertPath:    tc.certPath,
+		PrivKeyPath: tc.keyPath,
+	}
+}
+
 func TestID(t *testing.T) {
 	device := Device{
 		Endpoint:    "myendpoint",
@@ -57,3 +75,104 @@ func TestTelemetryTopic(t *testing.T) {
 		})
 	}
 }
+
+func TestNewClientWithOptions(t *testing.T) {
+	d := testDevice(t)
+	client, opts, err := d.NewClientWithOptions(func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetKeepAlive(45 * time.Second)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client == nil {
+		t.Fatalf("got nil client")
+	}
+
+	if opts.ClientID != "foo" {
+		t.Errorf("got client ID %q, want %q", opts.ClientID, "foo")
+	}
+	if got, want := opts.Servers[0].String(), "ssl://abc123-ats.iot.us-west-2.amazonaws.com:8883"; got != want {
+		t.Errorf("got broker %q, want %q", got, want)
+	}
+	if opts.TLSConfig.ServerName != d.Endpoint {
+		t.Errorf("got ServerName %q, want %q", opts.TLSConfig.ServerName, d.Endpoint)
+	}
+	if len(opts.TLSConfig.Certificates) != 1 {
+		t.Errorf("got %d client certs, want 1", len(opts.TLSConfig.Certificates))
+	}
+	if opts.KeepAlive != 45 {
+		t.Errorf("got keepalive %d, want 45", opts.KeepAlive)
+	}
+
+	// The returned options must not share the TLS config or broker URLs with the client.
+	opts.TLSConfig.ServerName = "changed"
+	opts.Servers[0].Host = "changed:8883"
+	r := client.OptionsReader()
+	if got := r.TLSConfig().ServerName; got != d.Endpoint {
+		t.Errorf("client's ServerName changed to %q with the returned options", got)
+	}
+	if got := r.Servers()[0].Host; got != "abc123-ats.iot.us-west-2.amazonaws.com:8883" {
+		t.Errorf("client's broker changed to %q with the returned options", got)
+	}
+}
+
+func TestNewClientInvalid(t *testing.T) {
+	d := testDevice(t)
+	d.PrivKeyPath = ""
+	if _, err := d.NewClient(); err == nil {
+		t.Errorf("got nil error, want non-nil")
+	}
+}
+
+func TestNewClientCustomAuthorizer(t *testing.T) {
+	d := testDevice(t)
+	d.CertPath = ""
+	d.PrivKeyPath = ""
+	d.CustomAuthorizer = "my-authorizer"
+
+	_, opts, err := d.NewClientWithOptions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(opts.TLSConfig.Certificates) != 0 {
+		t.Errorf("got %d client certs, want 0", len(opts.TLSConfig.Certificates))
+	}
+	if want := "?x-amz-customauthorizer-name=my-authorizer"; opts.Username != want {
+		t.Errorf("got username %q, want %q", opts.Username, want)
+	}
+}
+
+func TestConnectAsync(t *testing.T) {
+	client := newFakeClient()
+	client.Disconnect(0)
+	if err := <-ConnectAsync(client); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !client.IsConnected() {
+		t.Errorf("client not connected")
+	}
+
+	connErr := errors.New("not authorized")
+	client.connect = func() error { return connErr }
+	if err := <-ConnectAsync(client); !errors.Is(err, connErr) {
+		t.Errorf("got error %v, want %v", err, connErr)
+	}
+}
+
+func TestWaitConnected(t *testing.T) {
+	client := newFakeClient()
+	client.Disconnect(0)
+	if err := WaitConnected(client, 10*time.Millisecond); err == nil {
+		t.Errorf("got nil error for disconnected client, want non-nil")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		client.Connect()
+	}()
+	if err := WaitConnected(client, time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
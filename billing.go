@@ -0,0 +1,15 @@
+package awsiotcore
+
+// BillingBlockSize is the size of the increments in which AWS IoT Core meters messages. See
+// https://aws.amazon.com/iot-core/pricing/.
+const BillingBlockSize = 5 * 1024
+
+// BilledBlocks returns the number of messages AWS IoT Core bills for a message with a payload of payloadLen bytes.
+// Messages are metered in 5 KB increments, so for example an 8 KB message is billed as two messages. Every message
+// is billed as at least one, even if its payload is empty.
+func BilledBlocks(payloadLen int) int {
+	if payloadLen <= 0 {
+		return 1
+	}
+	return (payloadLen + BillingBlockSize - 1) / BillingBlockSize
+}
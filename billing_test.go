@@ -0,0 +1,28 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBilledBlocks(t *testing.T) {
+	cases := []struct {
+		payloadLen int
+		want       int
+	}{
+		{0, 1},
+		{1, 1},
+		{5 * 1024, 1},
+		{5*1024 + 1, 2},
+		{8 * 1024, 2},
+		{128 * 1024, 26},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.payloadLen), func(t *testing.T) {
+			if got := BilledBlocks(c.payloadLen); got != c.want {
+				t.Errorf("got %d, want %d", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package awsiotcore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// BinaryMessage is binary data that's carried in JSON as a base64 string. Use it as the type of a field in a
+// telemetry struct to round-trip binary sensor data. It's encoded as standard, padded base64, and decoding accepts
+// both the standard and URL-safe alphabets, with or without padding. Malformed base64 is an error.
+type BinaryMessage []byte
+
+func (m BinaryMessage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.StdEncoding.EncodeToString(m))
+}
+
+func (m *BinaryMessage) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*m = nil
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("awsiotcore: binary data must be a base64 string: %w", err)
+	}
+
+	b, err := decodeBase64(s)
+	if err != nil {
+		return err
+	}
+	*m = b
+	return nil
+}
+
+// DecodeBinaryPayload decodes a payload that consists solely of base64-encoded binary data, either bare or as a JSON
+// string. It returns an error, without decoding, if the decoded data would be longer than maxLen bytes. If maxLen
+// is 0 then the length isn't limited.
+func DecodeBinaryPayload(payload []byte, maxLen int) ([]byte, error) {
+	s := string(bytes.TrimSpace(payload))
+	if len(s) > 0 && s[0] == '"' {
+		if err := json.Unmarshal([]byte(s), &s); err != nil {
+			return nil, fmt.Errorf("awsiotcore: failed to parse binary payload: %w", err)
+		}
+	}
+
+	if maxLen > 0 && base64.RawStdEncoding.DecodedLen(len(strings.TrimRight(s, "="))) > maxLen {
+		return nil, fmt.Errorf("awsiotcore: binary payload is longer than %d bytes", maxLen)
+	}
+	return decodeBase64(s)
+}
+
+// decodeBase64 decodes s, which may use the standard or URL-safe alphabet, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	enc := base64.StdEncoding
+	for i := 0; i < len(s); i++ {
+		if s[i] == '-' || s[i] == '_' {
+			enc = base64.URLEncoding
+			break
+		}
+	}
+	if len(s)%4 != 0 {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+
+	b, err := enc.Strict().DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: malformed base64: %w", err)
+	}
+	return b, nil
+}
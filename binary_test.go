@@ -0,0 +1,72 @@
+package awsiotcore
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBinaryMessage(t *testing.T) {
+	type frame struct {
+		Data BinaryMessage `json:"data"`
+	}
+
+	want := []byte{0x00, 0xfb, 0xff, 0x10}
+	b, err := json.Marshal(frame{Data: want})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(b) != `{"data":"APv/EA=="}` {
+		t.Errorf("got %s, want standard padded base64", b)
+	}
+
+	cases := []struct {
+		in      string
+		wantErr bool
+	}{
+		{`{"data":"APv/EA=="}`, false},
+		{`{"data":"APv/EA"}`, false},
+		{`{"data":"APv_EA=="}`, false},
+		{`{"data":"APv_EA"}`, false},
+		{`{"data":"APv/E"}`, true},
+		{`{"data":"!!!!"}`, true},
+		{`{"data":42}`, true},
+	}
+	for _, c := range cases {
+		var got frame
+		err := json.Unmarshal([]byte(c.in), &got)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: got nil error, want non-nil", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.in, err)
+		} else if !bytes.Equal(got.Data, want) {
+			t.Errorf("%s: got %x, want %x", c.in, got.Data, want)
+		}
+	}
+}
+
+func TestDecodeBinaryPayload(t *testing.T) {
+	want := []byte{0x00, 0xfb, 0xff, 0x10}
+	for _, in := range []string{"APv/EA==", `"APv/EA=="`, " APv_EA\n"} {
+		got, err := DecodeBinaryPayload([]byte(in), 0)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", in, err)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%q: got %x, want %x", in, got, want)
+		}
+	}
+
+	if _, err := DecodeBinaryPayload([]byte("APv/EA=="), 3); err == nil {
+		t.Errorf("got nil error for payload over maxLen, want non-nil")
+	}
+	if _, err := DecodeBinaryPayload([]byte("APv/EA=="), 4); err != nil {
+		t.Errorf("unexpected error for payload at maxLen: %v", err)
+	}
+	if _, err := DecodeBinaryPayload([]byte(`"APv`), 0); err == nil {
+		t.Errorf("got nil error for malformed JSON, want non-nil")
+	}
+}
@@ -1,16 +1,26 @@
 package awsiotcore
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
 
 // MQTTBroker represents an MQTT server.
 type MQTTBroker struct {
-	Host string
-	Port int
+	// Scheme is the URL scheme used to connect to the server, e.g. "ssl" or "wss". If empty, "ssl" is used.
+	Scheme string
+	Host   string
+	Port   int
 }
 
 // URL returns the URL of the MQTT server.
 func (b *MQTTBroker) URL() string {
-	return fmt.Sprintf("tls://%s:%d", b.Host, b.Port)
+	scheme := b.Scheme
+	if scheme == "" {
+		scheme = "ssl"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(b.Host, strconv.Itoa(b.Port)))
 }
 
 // String returns a string representation of the MQTTBroker.
@@ -0,0 +1,38 @@
+package awsiotcore
+
+import (
+	"testing"
+)
+
+func TestBrokerURL(t *testing.T) {
+	cases := []struct {
+		name   string
+		broker MQTTBroker
+		want   string
+	}{
+		{
+			name:   "default_scheme",
+			broker: MQTTBroker{Host: "myendpoint", Port: 8883},
+			want:   "ssl://myendpoint:8883",
+		},
+		{
+			name:   "explicit_scheme",
+			broker: MQTTBroker{Scheme: "wss", Host: "myendpoint", Port: 443},
+			want:   "wss://myendpoint:443",
+		},
+		{
+			name:   "ipv6",
+			broker: MQTTBroker{Host: "2001:db8::1", Port: 8883},
+			want:   "ssl://[2001:db8::1]:8883",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.broker.URL()
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package awsiotcore
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// readCert reads and parses the PEM-encoded X.509 cert at certPath.
+func readCert(certPath string) (*x509.Certificate, error) {
+	certBytes, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("awsiotcore: cert file does not exist: %v", certPath)
+		}
+
+		return nil, fmt.Errorf("awsiotcore: failed to read cert: %v", err)
+	}
+
+	block, _ := pem.Decode(certBytes)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("awsiotcore: failed to decode PEM certificate")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// readCertBundle reads and parses every PEM-encoded X.509 cert in the file at path, such as a CA bundle.
+func readCertBundle(path string) ([]*x509.Certificate, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to read CA certs: %v", err)
+	}
+
+	certs, err := parseCertBundle(b)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse CA certs in %s: %w", path, err)
+	}
+	return certs, nil
+}
+
+// parseCertBundle parses every PEM-encoded X.509 cert in b. Blocks of other types are ignored.
+func parseCertBundle(b []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certs found")
+	}
+	return certs, nil
+}
+
+// CertID returns the ID that AWS IoT uses for the X.509 cert at certPath: the lowercase hex SHA-256 of the DER-encoded
+// cert. It's the ID to use with the DescribeCertificate API and in policies and logs.
+func CertID(certPath string) (string, error) {
+	cert, err := readCert(certPath)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Fingerprint returns a fingerprint of the public key in the X.509 cert at certPath: the lowercase hex SHA-256 of
+// its DER-encoded SubjectPublicKeyInfo. Unlike CertID it doesn't change when the cert is renewed with the same key,
+// so it's suited to identifying a device across systems.
+func Fingerprint(certPath string) (string, error) {
+	cert, err := readCert(certPath)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CertInfo describes an X.509 cert.
+type CertInfo struct {
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	// SerialNumber is the cert's serial number in hex.
+	SerialNumber string
+	// SANs are the cert's subject alternative names: DNS names, email addresses, IP addresses, and URIs.
+	SANs []string
+}
+
+// ReadCertInfo reads the PEM-encoded X.509 cert at certPath and describes it.
+func ReadCertInfo(certPath string) (*CertInfo, error) {
+	cert, err := readCert(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info := newCertInfo(cert)
+	return &info, nil
+}
+
+func newCertInfo(cert *x509.Certificate) CertInfo {
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	return CertInfo{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		SerialNumber: cert.SerialNumber.Text(16),
+		SANs:         sans,
+	}
+}
+
+// String returns a multi-line, human-readable description of the cert.
+func (c *CertInfo) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Subject:    %s\n", c.Subject)
+	fmt.Fprintf(&b, "Issuer:     %s\n", c.Issuer)
+	fmt.Fprintf(&b, "Serial:     %s\n", c.SerialNumber)
+	fmt.Fprintf(&b, "Not before: %s\n", c.NotBefore.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Not after:  %s\n", c.NotAfter.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "SANs:       %s", strings.Join(c.SANs, ", "))
+	return b.String()
+}
+
+// ValidateCABundle parses each cert in the CA bundle at path, e.g. the file CACerts points to, and returns those that
+// are expired or not yet valid, along with an error describing them. Such certs cause TLS verification errors that
+// don't say the bundle is to blame. If every cert is valid it returns nil, nil.
+func ValidateCABundle(path string) ([]CertInfo, error) {
+	certs, err := readCertBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var invalid []CertInfo
+	var problems []string
+	for _, cert := range certs {
+		switch {
+		case now.Before(cert.NotBefore):
+			problems = append(problems, fmt.Sprintf("%s is not valid until %v", cert.Subject, cert.NotBefore))
+		case now.After(cert.NotAfter):
+			problems = append(problems, fmt.Sprintf("%s expired at %v", cert.Subject, cert.NotAfter))
+		default:
+			continue
+		}
+		invalid = append(invalid, newCertInfo(cert))
+	}
+
+	if len(invalid) > 0 {
+		return invalid, fmt.Errorf("awsiotcore: CA bundle %s has invalid certs: %s", path, strings.Join(problems, "; "))
+	}
+	return nil, nil
+}
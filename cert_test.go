@@ -0,0 +1,199 @@
+package awsiotcore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testCert is a self-signed cert and its key, written to disk by writeTestCert.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certPath string
+	keyPath  string
+}
+
+// writeTestCert writes a self-signed cert with the given Common Name and validity period, and its private key, to
+// PEM files in a temporary directory.
+func writeTestCert(t *testing.T, cn string, notBefore, notAfter time.Time) testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1234),
+		Subject:      pkix.Name{CommonName: cn},
+		Issuer:       pkix.Name{CommonName: cn},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		DNSNames:     []string{cn + ".example.com"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	tc := testCert{
+		cert:     cert,
+		key:      key,
+		certPath: filepath.Join(dir, cn+".x509"),
+		keyPath:  filepath.Join(dir, cn+".pem"),
+	}
+	writeFile(t, tc.certPath, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	writeFile(t, tc.keyPath, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return tc
+}
+
+func TestDeviceIDFromCert(t *testing.T) {
+	tc := writeTestCert(t, "foo", time.Now(), time.Now().Add(time.Hour))
+
+	got, err := DeviceIDFromCert(tc.certPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "foo" {
+		t.Errorf("got %q, want %q", got, "foo")
+	}
+
+	if _, err := DeviceIDFromCert(filepath.Join(t.TempDir(), "missing.x509")); err == nil {
+		t.Errorf("got nil error for missing cert, want non-nil")
+	}
+}
+
+func TestCertID(t *testing.T) {
+	tc := writeTestCert(t, "foo", time.Now(), time.Now().Add(time.Hour))
+
+	got, err := CertID(tc.certPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum := sha256.Sum256(tc.cert.Raw)
+	if want := hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tc := writeTestCert(t, "foo", time.Now(), time.Now().Add(time.Hour))
+
+	got, err := Fingerprint(tc.certPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum := sha256.Sum256(tc.cert.RawSubjectPublicKeyInfo)
+	if want := hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if id, _ := CertID(tc.certPath); got == id {
+		t.Errorf("fingerprint is the same as the cert ID")
+	}
+}
+
+func TestCertIDNotPEM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.x509")
+	if err := os.WriteFile(path, []byte("not a cert"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CertID(path); err == nil {
+		t.Errorf("got nil error, want non-nil")
+	}
+}
+
+func TestReadCertInfo(t *testing.T) {
+	notBefore := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tc := writeTestCert(t, "foo", notBefore, notAfter)
+
+	info, err := ReadCertInfo(tc.certPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &CertInfo{
+		Subject:      "CN=foo",
+		Issuer:       "CN=foo",
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		SerialNumber: "4d2",
+		SANs:         []string{"foo.example.com"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+
+	wantString := `Subject:    CN=foo
+Issuer:     CN=foo
+Serial:     4d2
+Not before: 2023-01-01T00:00:00Z
+Not after:  2024-01-01T00:00:00Z
+SANs:       foo.example.com`
+	if got := info.String(); got != wantString {
+		t.Errorf("got String()\n%s\nwant\n%s", got, wantString)
+	}
+}
+
+func TestValidateCABundle(t *testing.T) {
+	valid := writeTestCert(t, "valid", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	expired := writeTestCert(t, "expired", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	future := writeTestCert(t, "future", time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+
+	bundle := func(certs ...testCert) string {
+		var pems []byte
+		for _, c := range certs {
+			b, err := os.ReadFile(c.certPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pems = append(pems, b...)
+		}
+		path := filepath.Join(t.TempDir(), "roots.pem")
+		writeFile(t, path, string(pems))
+		return path
+	}
+
+	if invalid, err := ValidateCABundle(bundle(valid)); err != nil || invalid != nil {
+		t.Errorf("got %v, %v for a valid bundle, want nil, nil", invalid, err)
+	}
+
+	invalid, err := ValidateCABundle(bundle(valid, expired, future))
+	if err == nil {
+		t.Errorf("got nil error for a bundle with invalid certs, want non-nil")
+	}
+	if len(invalid) != 2 || invalid[0].Subject != "CN=expired" || invalid[1].Subject != "CN=future" {
+		t.Errorf("got invalid certs %v, want CN=expired and CN=future", invalid)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	writeFile(t, empty, "not a cert")
+	if _, err := ValidateCABundle(empty); err == nil {
+		t.Errorf("got nil error for a bundle with no certs, want non-nil")
+	}
+}
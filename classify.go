@@ -0,0 +1,106 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+// ConnectionErrorClass is a broad category of connection error, as returned by ClassifyConnectionError.
+type ConnectionErrorClass int
+
+const (
+	// ConnectionErrorUnknown is an error that doesn't fall into any other class, or a nil error.
+	ConnectionErrorUnknown ConnectionErrorClass = iota
+	// ConnectionErrorNetworkUnreachable means the broker couldn't be reached: DNS resolution failed, or the TCP
+	// connection was refused or timed out.
+	ConnectionErrorNetworkUnreachable
+	// ConnectionErrorTLSHandshakeFailure means the TLS handshake failed for a reason other than the broker rejecting
+	// the device's cert, e.g. the broker's cert didn't verify against CACerts.
+	ConnectionErrorTLSHandshakeFailure
+	// ConnectionErrorAuthFailure means the broker rejected the device's credentials, either its cert during the TLS
+	// handshake or, with a custom authorizer, its username and password.
+	ConnectionErrorAuthFailure
+	// ConnectionErrorPolicyDenied means the broker closed the connection without saying why, which is what AWS IoT
+	// does when the device's policy doesn't allow what it tried to do. It's inferred, so other causes are possible.
+	ConnectionErrorPolicyDenied
+)
+
+func (c ConnectionErrorClass) String() string {
+	switch c {
+	case ConnectionErrorNetworkUnreachable:
+		return "NetworkUnreachable"
+	case ConnectionErrorTLSHandshakeFailure:
+		return "TLSHandshakeFailure"
+	case ConnectionErrorAuthFailure:
+		return "AuthFailure"
+	case ConnectionErrorPolicyDenied:
+		return "PolicyDenied"
+	default:
+		return "Unknown"
+	}
+}
+
+// Guidance returns a suggestion of what to check for errors of the class.
+func (c ConnectionErrorClass) Guidance() string {
+	switch c {
+	case ConnectionErrorNetworkUnreachable:
+		return "check that the endpoint is correct and that the network allows outbound connections to it on port 8883"
+	case ConnectionErrorTLSHandshakeFailure:
+		return "check that CACerts contains Amazon's root CA certs and that the endpoint is an ATS endpoint"
+	case ConnectionErrorAuthFailure:
+		return "check that the device's cert is registered with AWS IoT, active, unexpired, and attached to the thing"
+	case ConnectionErrorPolicyDenied:
+		return "check that the policy attached to the device's cert allows iot:Connect with its client ID and the topics it uses, and that no other client is connected with the same client ID"
+	default:
+		return "run Device.DiagnoseConnection for more detail"
+	}
+}
+
+// ClassifyConnectionError sorts an error from connecting, or the error passed to a connection lost handler, into a
+// ConnectionErrorClass, which is more actionable than an error like EOF or a TLS alert.
+func ClassifyConnectionError(err error) ConnectionErrorClass {
+	if err == nil {
+		return ConnectionErrorUnknown
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) ||
+		isUnreachableErrno(err) ||
+		(errors.As(err, &opErr) && opErr.Op == "dial") {
+		return ConnectionErrorNetworkUnreachable
+	}
+
+	if errors.Is(err, packets.ErrorRefusedNotAuthorised) || errors.Is(err, packets.ErrorRefusedBadUsernameOrPassword) {
+		return ConnectionErrorAuthFailure
+	}
+
+	msg := err.Error()
+	// The broker rejecting the device's cert shows up as a TLS alert, which crypto/tls reports as "remote error".
+	for _, alert := range []string{"bad certificate", "certificate required", "certificate expired", "certificate revoked", "unknown certificate authority"} {
+		if strings.Contains(msg, "remote error: tls: "+alert) {
+			return ConnectionErrorAuthFailure
+		}
+	}
+
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &recordErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) ||
+		strings.Contains(msg, "tls: ") || strings.Contains(msg, "x509: ") {
+		return ConnectionErrorTLSHandshakeFailure
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || isConnResetErrno(err) {
+		return ConnectionErrorPolicyDenied
+	}
+
+	return ConnectionErrorUnknown
+}
@@ -0,0 +1,18 @@
+//go:build !plan9
+
+package awsiotcore
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isUnreachableErrno reports whether err is a connection refused or network or host unreachable error from the OS.
+func isUnreachableErrno(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH)
+}
+
+// isConnResetErrno reports whether err is a connection reset error from the OS.
+func isConnResetErrno(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,8 @@
+package awsiotcore
+
+// Plan 9 reports network errors as strings rather than errnos, so errors it returns that aren't *net.DNSError or
+// dial errors classify as ConnectionErrorUnknown.
+
+func isUnreachableErrno(err error) bool { return false }
+
+func isConnResetErrno(err error) bool { return false }
@@ -0,0 +1,27 @@
+//go:build !plan9
+
+package awsiotcore
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyConnectionErrorErrno(t *testing.T) {
+	cases := []struct {
+		err  error
+		want ConnectionErrorClass
+	}{
+		{os.NewSyscallError("connect", syscall.ECONNREFUSED), ConnectionErrorNetworkUnreachable},
+		{os.NewSyscallError("connect", syscall.EHOSTUNREACH), ConnectionErrorNetworkUnreachable},
+		{&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, ConnectionErrorPolicyDenied},
+	}
+
+	for _, c := range cases {
+		if got := ClassifyConnectionError(c.err); got != c.want {
+			t.Errorf("ClassifyConnectionError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
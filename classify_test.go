@@ -0,0 +1,35 @@
+package awsiotcore
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+func TestClassifyConnectionError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want ConnectionErrorClass
+	}{
+		{nil, ConnectionErrorUnknown},
+		{errors.New("something else"), ConnectionErrorUnknown},
+		{&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, ConnectionErrorNetworkUnreachable},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("i/o timeout")}, ConnectionErrorNetworkUnreachable},
+		{fmt.Errorf("network Error : %w", x509.UnknownAuthorityError{}), ConnectionErrorTLSHandshakeFailure},
+		{errors.New("tls: handshake failure"), ConnectionErrorTLSHandshakeFailure},
+		{errors.New("remote error: tls: bad certificate"), ConnectionErrorAuthFailure},
+		{packets.ErrorRefusedNotAuthorised, ConnectionErrorAuthFailure},
+		{fmt.Errorf("connection lost: %w", io.EOF), ConnectionErrorPolicyDenied},
+	}
+
+	for _, c := range cases {
+		if got := ClassifyConnectionError(c.err); got != c.want {
+			t.Errorf("ClassifyConnectionError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
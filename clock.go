@@ -0,0 +1,52 @@
+package awsiotcore
+
+import (
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Clock tells the time and makes timers. The Device's time-dependent methods, e.g. the timestamps of
+// PublishLifecycle and WithTelemetryEnvelope, the waits of WatchCertExpiry and StartHeartbeat, and the rate limit of
+// Publisher.Interval, use it rather than the time package so that tests can control the time with WithClock.
+// Durations measured for reporting, like those of Ping and DiagnoseConnection, always use the system clock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer made by a Clock. Like a time.Timer, it sends the time on the channel returned by C once it fires.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// WithClock returns an option that makes the Device use c rather than the system clock. It's meant for tests of
+// code that depends on the time, which can then advance c instead of sleeping. Note that it sets the clock on the
+// Device itself, so it affects all of the Device's methods and not just the client being created.
+func WithClock(c Clock) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		d.clock = c
+		return nil
+	}
+}
+
+// clockOrSystem returns the Device's clock, or the system clock if none was set with WithClock.
+func (d *Device) clockOrSystem() Clock {
+	if d.clock == nil {
+		return systemClock{}
+	}
+	return d.clock
+}
@@ -0,0 +1,110 @@
+package awsiotcore
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only changes when advance is called.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// timerMade receives a value whenever a timer is made, so that tests can wait for code to start waiting.
+	timerMade chan struct{}
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+func (t *fakeTimer) Stop() bool          { return true }
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, timerMade: make(chan struct{}, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	c.timerMade <- struct{}{}
+	return t
+}
+
+// advance moves the clock forward by d and fires the timers that are due.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	var pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2023, 4, 22, 18, 30, 0, 0, time.UTC)
+	d := &Device{DeviceID: "foo"}
+	if err := WithClock(newFakeClock(now))(d, testOpts(d)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client := newFakeClient()
+	if err := d.PublishLifecycle(client, LifecycleStartup); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got lifecycleMessage
+	if err := json.Unmarshal(client.messages()[0].payload, &got); err != nil {
+		t.Fatalf("failed to parse payload: %v", err)
+	}
+	if !got.Timestamp.Equal(now) {
+		t.Errorf("got timestamp %v, want %v", got.Timestamp, now)
+	}
+}
+
+func TestWatchCertExpiryFakeClock(t *testing.T) {
+	now := time.Now()
+	tc := writeTestCert(t, "foo", now.Add(-time.Hour), now.Add(3*time.Hour))
+	clock := newFakeClock(now)
+	d := &Device{DeviceID: "foo", CertPath: tc.certPath, clock: clock}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	expiring := make(chan struct{}, 1)
+	go d.WatchCertExpiry(ctx, time.Hour, func() { expiring <- struct{}{} })
+
+	// The cert is 2h from the warning, so the watcher rechecks after an hour without calling onExpiring.
+	<-clock.timerMade
+	clock.advance(time.Hour)
+	<-clock.timerMade
+	select {
+	case <-expiring:
+		t.Fatalf("onExpiring called an hour before the warning")
+	default:
+	}
+
+	clock.advance(time.Hour)
+	select {
+	case <-expiring:
+	case <-time.After(time.Second):
+		t.Fatalf("onExpiring not called once the cert is within warnBefore of expiring")
+	}
+}
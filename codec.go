@@ -0,0 +1,43 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Codec encodes and decodes message payloads. Implement it to publish payloads in formats this package doesn't
+// provide, e.g. protocol buffers.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSON is a Codec that uses encoding/json.
+	JSON Codec = jsonCodec{}
+
+	// CBOR is a Codec that uses github.com/fxamacker/cbor/v2. Note that AWS IoT rules can decode CBOR payloads
+	// using the decode function; see https://docs.aws.amazon.com/iot/latest/developerguide/binary-payloads.html.
+	CBOR Codec = cborCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type cborCodec struct{}
+
+func (cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
@@ -0,0 +1,51 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// CommandTopic returns the MQTT topic on which the device receives commands.
+func (d *Device) CommandTopic() string {
+	return d.bridgeTopic(fmt.Sprintf("things/%v/commands", d.DeviceID))
+}
+
+// CommandAckTopic returns the MQTT topic to which the device publishes acknowledgements of commands.
+func (d *Device) CommandAckTopic() string {
+	return d.CommandTopic() + "/ack"
+}
+
+// commandError is the acknowledgement published when a command handler returns an error.
+type commandError struct {
+	Error string `json:"error"`
+}
+
+// HandleCommands subscribes to the device's command topic and calls handler with the payload of each command. If the
+// handler succeeds its ack is published to the command ack topic; if it fails, a JSON object of the form
+// {"error": "<message>"} is published instead. Acks are published at QoS 1, or the QoS TopicQoS gives for the ack
+// topic.
+//
+// Acks are published from a new goroutine, since paho's Publish can block and blocking in a message handler stalls
+// the client's message routing. Failures to publish them are logged to paho's ERROR logger. Like Subscribe,
+// HandleCommands returns a *QoSDowngradeError if the broker grants the command subscription a lower QoS than 1.
+func (d *Device) HandleCommands(client mqtt.Client, handler func(cmd []byte) (ack []byte, err error)) error {
+	h := func(c mqtt.Client, m mqtt.Message) {
+		ack, err := handler(m.Payload())
+		if err != nil {
+			// Marshaling a struct with a single string field can't fail.
+			ack, _ = json.Marshal(commandError{Error: err.Error()})
+		}
+
+		ackTopic := d.CommandAckTopic()
+		go func() {
+			t := c.Publish(ackTopic, d.topicQoS(ackTopic, 1), false, ack)
+			if t.Wait() && t.Error() != nil {
+				mqtt.ERROR.Printf("awsiotcore: failed to publish command ack: %v", t.Error())
+			}
+		}()
+	}
+
+	return Subscribe(client, d.CommandTopic(), 1, h)
+}
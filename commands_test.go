@@ -0,0 +1,89 @@
+package awsiotcore
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+// acks makes client report each message published to it on the returned channel. HandleCommands publishes acks
+// from a new goroutine, so tests must wait for them.
+func acks(client *fakeClient) <-chan *fakeMessage {
+	ch := make(chan *fakeMessage, 16)
+	client.respond = func(c *fakeClient, m *fakeMessage) { ch <- m }
+	return ch
+}
+
+// receiveAcks returns the next n messages from ch, failing the test if they don't arrive in time.
+func receiveAcks(t *testing.T, ch <-chan *fakeMessage, n int) []*fakeMessage {
+	t.Helper()
+	var msgs []*fakeMessage
+	for len(msgs) < n {
+		select {
+		case m := <-ch:
+			msgs = append(msgs, m)
+		case <-time.After(time.Second):
+			t.Fatalf("got %d acks, want %d", len(msgs), n)
+		}
+	}
+	return msgs
+}
+
+func TestHandleCommands(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	ch := acks(client)
+	err := d.HandleCommands(client, func(cmd []byte) ([]byte, error) {
+		if string(cmd) == "reboot" {
+			return []byte("rebooting"), nil
+		}
+		return nil, errors.New("unknown command")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client.deliver("things/foo/commands", []byte("reboot"))
+	client.deliver("things/foo/commands", []byte("explode"))
+
+	var payloads []string
+	for _, m := range receiveAcks(t, ch, 2) {
+		if m.topic != "things/foo/commands/ack" {
+			t.Errorf("got ack topic %q, want %q", m.topic, "things/foo/commands/ack")
+		}
+		payloads = append(payloads, string(m.payload))
+	}
+
+	// Acks are published concurrently, so they may arrive in either order.
+	sort.Strings(payloads)
+	if want := []string{"rebooting", `{"error":"unknown command"}`}; payloads[0] != want[0] || payloads[1] != want[1] {
+		t.Errorf("got acks %q, want %q", payloads, want)
+	}
+}
+
+func TestHandleCommandsTopicQoS(t *testing.T) {
+	d := &Device{DeviceID: "foo", TopicQoS: map[string]byte{"things/+/commands/ack": 0}}
+	client := newFakeClient()
+	ch := acks(client)
+	if err := d.HandleCommands(client, func(cmd []byte) ([]byte, error) { return []byte("ok"), nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client.deliver("things/foo/commands", []byte("reboot"))
+	if m := receiveAcks(t, ch, 1)[0]; m.qos != 0 {
+		t.Errorf("got ack at QoS %d, want the QoS from TopicQoS, 0", m.qos)
+	}
+}
+
+func TestHandleCommandsQoSDowngrade(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	client.granted = map[string]byte{"things/foo/commands": 0}
+
+	var downErr *QoSDowngradeError
+	err := d.HandleCommands(client, func(cmd []byte) ([]byte, error) { return nil, nil })
+	if !errors.As(err, &downErr) || downErr.Filter != "things/foo/commands" {
+		t.Errorf("got error %v, want a QoSDowngradeError for things/foo/commands", err)
+	}
+}
@@ -0,0 +1,117 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// DeviceFromConfig reads a Device from the JSON file at path. The Device must be valid; see Validate.
+func DeviceFromConfig(path string) (*Device, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to read config: %w", err)
+	}
+
+	var d Device
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse config %s: %w", path, err)
+	}
+
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// DevicesFromConfig reads several named Devices from the JSON file at path, which must contain an object mapping
+// names to Device configs in the format DeviceFromConfig reads. This lets a gateway that represents several devices
+// keep their configs in one file. Every Device must be valid; see Validate.
+func DevicesFromConfig(path string) (map[string]*Device, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to read config: %w", err)
+	}
+
+	var devices map[string]*Device
+	if err := json.Unmarshal(b, &devices); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse config %s: %w", path, err)
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("awsiotcore: config %s contains no devices", path)
+	}
+
+	for name, d := range devices {
+		if d == nil {
+			return nil, fmt.Errorf("awsiotcore: device %q in %s is null", name, path)
+		}
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("awsiotcore: device %q in %s is invalid: %w", name, path, err)
+		}
+	}
+
+	return devices, nil
+}
+
+// DeviceCache keeps a copy of the last Device that was successfully loaded so that it can be used if loading fails
+// later, e.g. because a config file was corrupted by an interrupted write. This lets a device come online with its
+// last known good config rather than not at all.
+type DeviceCache struct {
+	// Path is the file in which the last known good Device is stored as JSON.
+	Path string
+}
+
+// Load calls load and, if it succeeds, saves the Device to the cache and returns it. If load fails then the cached
+// Device is returned instead and the fallback is logged to paho's WARN logger. If there is no usable cached Device
+// then the error from load is returned. For example:
+//
+//	cache := awsiotcore.DeviceCache{Path: "/var/lib/mydevice/last-good.json"}
+//	d, err := cache.Load(func() (*awsiotcore.Device, error) {
+//		return awsiotcore.DeviceFromConfig("/etc/mydevice/device.json")
+//	})
+func (c *DeviceCache) Load(load func() (*Device, error)) (*Device, error) {
+	d, err := load()
+	if err == nil {
+		if serr := c.save(d); serr != nil {
+			mqtt.WARN.Printf("awsiotcore: failed to cache device config: %v", serr)
+		}
+		return d, nil
+	}
+
+	cached, cerr := DeviceFromConfig(c.Path)
+	if cerr != nil {
+		return nil, err
+	}
+
+	mqtt.WARN.Printf("awsiotcore: failed to load device config, using cached config from %s: %v", c.Path, err)
+	return cached, nil
+}
+
+// save writes d to the cache file. It writes to a temporary file first and renames it so that the cache itself is
+// never left half-written.
+func (c *DeviceCache) save(d *Device) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), filepath.Base(c.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.Path)
+}
@@ -0,0 +1,99 @@
+package awsiotcore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validConfig = `{
+  "Endpoint": "abc123-ats.iot.us-west-2.amazonaws.com",
+  "device_id": "foo",
+  "ca_certs_path": "roots.pem",
+  "cert_path": "foo.x509",
+  "priv_key_path": "foo.pem"
+}`
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeviceFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "device.json")
+	writeFile(t, path, validConfig)
+
+	d, err := DeviceFromConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &Device{
+		Endpoint:    "abc123-ats.iot.us-west-2.amazonaws.com",
+		DeviceID:    "foo",
+		CACerts:     "roots.pem",
+		CertPath:    "foo.x509",
+		PrivKeyPath: "foo.pem",
+	}
+	if !d.Equal(want) {
+		t.Errorf("got %+v, want %+v", d, want)
+	}
+}
+
+func TestDevicesFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "devices.json")
+	writeFile(t, path, `{"sensor": `+validConfig+`, "actuator": `+strings.Replace(validConfig, `"foo"`, `"bar"`, 1)+`}`)
+
+	devices, err := DevicesFromConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 2 || devices["sensor"].DeviceID != "foo" || devices["actuator"].DeviceID != "bar" {
+		t.Errorf("got %v, want sensor foo and actuator bar", devices)
+	}
+
+	writeFile(t, path, `{"sensor": `+validConfig+`, "broken": {"device_id": "baz"}}`)
+	if _, err := DevicesFromConfig(path); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("got error %v, want one naming the invalid device", err)
+	}
+
+	writeFile(t, path, `{}`)
+	if _, err := DevicesFromConfig(path); err == nil {
+		t.Errorf("got nil error for config with no devices, want non-nil")
+	}
+}
+
+func TestDeviceCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "device.json")
+	cache := DeviceCache{Path: filepath.Join(dir, "cache.json")}
+	load := func() (*Device, error) {
+		return DeviceFromConfig(path)
+	}
+
+	// With no cache yet, a bad config is an error.
+	writeFile(t, path, "{")
+	if _, err := cache.Load(load); err == nil {
+		t.Errorf("got nil error with bad config and no cache, want non-nil")
+	}
+
+	writeFile(t, path, validConfig)
+	good, err := cache.Load(load)
+	if err != nil {
+		t.Fatalf("unexpected error with good config: %v", err)
+	}
+
+	writeFile(t, path, "{")
+	got, err := cache.Load(load)
+	if err != nil {
+		t.Fatalf("unexpected error with bad config and good cache: %v", err)
+	}
+	if !got.Equal(good) {
+		t.Errorf("got %+v, want %+v", got, good)
+	}
+}
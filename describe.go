@@ -0,0 +1,31 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Describe returns a multi-line, human-readable summary of the device's effective configuration: its endpoint, thing
+// name, client ID, main topics, and cert, e.g. for a CLI's status command. The client ID is the one NewClient uses
+// without options; options such as WithClientIDSuffix change it. If the cert can't be read, the error is shown in
+// its place.
+func (d *Device) Describe() string {
+	var b strings.Builder
+	broker := d.Broker()
+	fmt.Fprintf(&b, "Endpoint:        %s\n", broker.URL())
+	fmt.Fprintf(&b, "Thing name:      %s\n", d.ID())
+	fmt.Fprintf(&b, "Client ID:       %s\n", d.DeviceID)
+	fmt.Fprintf(&b, "Telemetry topic: %s\n", d.TelemetryTopic())
+	fmt.Fprintf(&b, "Shadow topic:    %s\n", d.ShadowTopic("update"))
+	fmt.Fprintf(&b, "Command topic:   %s\n", d.CommandTopic())
+
+	if d.CertPath == "" {
+		fmt.Fprintf(&b, "Cert:            none (custom authorizer %s)", d.CustomAuthorizer)
+	} else if cert, err := readCert(d.CertPath); err != nil {
+		fmt.Fprintf(&b, "Cert:            %s (%v)", d.CertPath, err)
+	} else {
+		fmt.Fprintf(&b, "Cert:            %s (CN %s, expires %s)", d.CertPath, cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package awsiotcore
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tc := writeTestCert(t, "foo", time.Now().Add(-time.Hour), notAfter)
+	d := &Device{
+		Endpoint: "abc123-ats.iot.us-west-2.amazonaws.com",
+		DeviceID: "foo",
+		CertPath: tc.certPath,
+	}
+
+	got := d.Describe()
+	for _, want := range []string{
+		"Endpoint:        ssl://abc123-ats.iot.us-west-2.amazonaws.com:8883\n",
+		"Thing name:      foo\n",
+		"Telemetry topic: things/foo/telemetry\n",
+		"Shadow topic:    $aws/things/foo/shadow/update\n",
+		"(CN foo, expires 2030-01-01T00:00:00Z)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("description doesn't contain %q:\n%s", want, got)
+		}
+	}
+
+	d.CertPath = "nonexistent.x509"
+	if got := d.Describe(); !strings.Contains(got, "Cert:            nonexistent.x509 (") {
+		t.Errorf("description doesn't show the cert error:\n%s", got)
+	}
+}
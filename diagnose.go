@@ -0,0 +1,181 @@
+package awsiotcore
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errSkipped is the error of a diagnostic step that wasn't run because an earlier step it depends on failed.
+var errSkipped = errors.New("skipped")
+
+// ReportStep is the result of one step of DiagnoseConnection.
+type ReportStep struct {
+	Name string
+	// Detail describes what the step found, e.g. the addresses the endpoint resolved to.
+	Detail   string
+	Err      error
+	Duration time.Duration
+}
+
+// OK reports whether the step succeeded.
+func (s ReportStep) OK() bool {
+	return s.Err == nil
+}
+
+// Report is the result of DiagnoseConnection.
+type Report struct {
+	Steps []ReportStep
+}
+
+// OK reports whether every step succeeded.
+func (r *Report) OK() bool {
+	for _, s := range r.Steps {
+		if !s.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a human-readable summary of the report, one line per step.
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, s := range r.Steps {
+		status := "OK"
+		if errors.Is(s.Err, errSkipped) {
+			status = "SKIPPED"
+		} else if s.Err != nil {
+			status = "FAIL"
+		}
+
+		fmt.Fprintf(&b, "%-16s %-7s", s.Name, status)
+		if s.Err != nil && !errors.Is(s.Err, errSkipped) {
+			fmt.Fprintf(&b, " %v", s.Err)
+		} else if s.Detail != "" {
+			fmt.Fprintf(&b, " %s", s.Detail)
+		}
+		fmt.Fprintf(&b, " (%v)\n", s.Duration.Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+// DiagnoseConnection checks, step by step, the things that commonly prevent a device from connecting: that its cert
+// is within its validity period and its Common Name matches the device ID, that the endpoint resolves, that the
+// broker's port is reachable, and that a TLS handshake with the device's cert succeeds. Steps that depend on a failed
+// step are skipped. It returns a report of every step along with the error of the first step that failed, if any.
+// It does not connect over MQTT, so it says nothing about whether the device's policy allows it to connect.
+func (d *Device) DiagnoseConnection(ctx context.Context) (*Report, error) {
+	r := &Report{}
+	run := func(name string, skip bool, f func() (string, error)) bool {
+		if skip {
+			r.Steps = append(r.Steps, ReportStep{Name: name, Err: errSkipped})
+			return false
+		}
+
+		start := time.Now()
+		detail, err := f()
+		r.Steps = append(r.Steps, ReportStep{Name: name, Detail: detail, Err: err, Duration: time.Since(start)})
+		return err == nil
+	}
+
+	if d.CertPath != "" {
+		cert, err := readCert(d.CertPath)
+		run("cert", false, func() (string, error) {
+			return d.CertPath, err
+		})
+		run("cert validity", err != nil, func() (string, error) {
+			now := d.clockOrSystem().Now()
+			if now.Before(cert.NotBefore) {
+				return "", fmt.Errorf("cert is not valid until %v", cert.NotBefore)
+			}
+			if now.After(cert.NotAfter) {
+				return "", fmt.Errorf("cert expired at %v", cert.NotAfter)
+			}
+			return fmt.Sprintf("valid until %v", cert.NotAfter), nil
+		})
+		run("cert name", err != nil, func() (string, error) {
+			if cert.Subject.CommonName != d.DeviceID {
+				return "", fmt.Errorf("cert Common Name %q does not match device ID %q", cert.Subject.CommonName, d.DeviceID)
+			}
+			return cert.Subject.CommonName, nil
+		})
+	}
+
+	broker := d.Broker()
+	var addrs []string
+	resolved := run("dns", false, func() (string, error) {
+		var err error
+		addrs, err = net.DefaultResolver.LookupHost(ctx, broker.Host)
+		return strings.Join(addrs, ", "), err
+	})
+
+	var conn net.Conn
+	reachable := run("tcp", !resolved, func() (string, error) {
+		var dialer net.Dialer
+		var err error
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port)))
+		if err != nil {
+			return "", err
+		}
+		return conn.RemoteAddr().String(), nil
+	})
+	if conn != nil {
+		defer conn.Close()
+	}
+
+	run("tls", !reachable, func() (string, error) {
+		return d.tlsHandshake(ctx, conn)
+	})
+
+	for _, s := range r.Steps {
+		if s.Err != nil && !errors.Is(s.Err, errSkipped) {
+			return r, fmt.Errorf("awsiotcore: %s check failed: %w", s.Name, s.Err)
+		}
+	}
+	return r, nil
+}
+
+// tlsHandshake performs a TLS handshake over conn using the TLS config NewClient would use. It returns a
+// description of the server's cert.
+func (d *Device) tlsHandshake(ctx context.Context, conn net.Conn) (string, error) {
+	opts, err := d.clientOptions()
+	if err != nil {
+		return "", err
+	}
+
+	tlsConn := tls.Client(conn, opts.TLSConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return "", err
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return "", fmt.Errorf("server presented no certs")
+	}
+	return fmt.Sprintf("server cert %s issued by %s", state.PeerCertificates[0].Subject, state.PeerCertificates[0].Issuer), nil
+}
+
+// TestTLSHandshake dials the device's broker, performs a TLS handshake using the TLS config NewClient would use, and
+// closes the connection. It returns an error if the handshake fails, e.g. because the server's cert doesn't chain to
+// CACerts or doesn't match the endpoint, which isolates TLS problems from MQTT ones. Like DiagnoseConnection, it says
+// nothing about whether the device's policy allows it to connect.
+func (d *Device) TestTLSHandshake(ctx context.Context) error {
+	broker := d.Broker()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port)))
+	if err != nil {
+		return fmt.Errorf("awsiotcore: failed to dial %s: %w", broker.URL(), err)
+	}
+	defer conn.Close()
+
+	if _, err := d.tlsHandshake(ctx, conn); err != nil {
+		return fmt.Errorf("awsiotcore: TLS handshake with %s failed: %w", broker.URL(), err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDiagnoseConnection(t *testing.T) {
+	d := testDevice(t)
+	// The .invalid TLD is guaranteed never to resolve.
+	d.Endpoint = "abc123-ats.iot.example.invalid"
+	expired := writeTestCert(t, "bar", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	d.CertPath = expired.certPath
+	d.PrivKeyPath = expired.keyPath
+
+	r, err := d.DiagnoseConnection(context.Background())
+	if err == nil {
+		t.Fatalf("got nil error, want non-nil")
+	}
+	if r.OK() {
+		t.Errorf("report is OK, want not OK")
+	}
+
+	want := map[string]string{
+		"cert":          "ok",
+		"cert validity": "fail",
+		"cert name":     "fail",
+		"dns":           "fail",
+		"tcp":           "skipped",
+		"tls":           "skipped",
+	}
+	if len(r.Steps) != len(want) {
+		t.Fatalf("got %d steps, want %d:\n%s", len(r.Steps), len(want), r)
+	}
+	for _, s := range r.Steps {
+		got := "ok"
+		if errors.Is(s.Err, errSkipped) {
+			got = "skipped"
+		} else if s.Err != nil {
+			got = "fail"
+		}
+
+		if got != want[s.Name] {
+			t.Errorf("step %q: got %s, want %s", s.Name, got, want[s.Name])
+		}
+	}
+}
+
+func TestTestTLSHandshake(t *testing.T) {
+	d := testDevice(t)
+	d.Endpoint = "abc123-ats.iot.example.invalid"
+
+	var dnsErr *net.DNSError
+	if err := d.TestTLSHandshake(context.Background()); !errors.As(err, &dnsErr) {
+		t.Errorf("got error %v, want a DNS error", err)
+	}
+}
@@ -0,0 +1,143 @@
+package awsiotcore
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// pinnedResolver caches the addresses of hosts and refreshes them once they're older than refresh.
+type pinnedResolver struct {
+	refresh time.Duration
+	lookup  func(ctx context.Context, host string) ([]string, error)
+
+	mu    sync.Mutex
+	hosts map[string]*pinnedHost
+}
+
+// pinnedHost is a pinnedResolver's cache entry for a host.
+type pinnedHost struct {
+	addrs      []string
+	resolvedAt time.Time
+}
+
+// resolve returns the cached addresses of host, looking them up again first if they're stale. If the lookup fails,
+// stale addresses are returned rather than none.
+func (r *pinnedResolver) resolve(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hosts == nil {
+		r.hosts = make(map[string]*pinnedHost)
+	}
+	h, ok := r.hosts[host]
+	if !ok {
+		h = &pinnedHost{}
+		r.hosts[host] = h
+	}
+
+	if len(h.addrs) > 0 && time.Since(h.resolvedAt) < r.refresh {
+		return h.addrs, nil
+	}
+
+	addrs, err := r.lookup(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		if len(h.addrs) > 0 {
+			return h.addrs, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no addresses for %s", host)
+		}
+		return nil, fmt.Errorf("awsiotcore: failed to resolve %s: %w", host, err)
+	}
+
+	h.addrs = addrs
+	h.resolvedAt = time.Now()
+	return addrs, nil
+}
+
+// WithPinnedDNS returns an option that resolves the endpoint once, when the client is created, and connects to the
+// resolved addresses rather than looking the endpoint up again on every reconnect. The addresses are refreshed on
+// the next connection attempt after refresh has passed. If the endpoint can't be resolved when the client is
+// created (say, because the network isn't up yet), resolution is retried on each connection attempt.
+//
+// This saves a DNS round trip per reconnect, which matters on high-latency links, but it defeats AWS's DNS-based
+// load balancing for up to refresh, so don't make refresh too long. It only supports TLS brokers (schemes ssl, tls,
+// mqtts and tcps), not WebSockets.
+//
+// The host connected to is that of the broker URL, so options that change the broker, like
+// WithEndpointOverrideForTesting, work with it. Since it opens connections itself, paho ignores the TLS config
+// returned by connect attempt handlers, so it can't be combined with WithConnectAttemptHandler or options built on
+// it, like WithFallbackEndpoint; whichever of them comes second returns an error. For the same reason it can't be
+// combined with WithoutSNI.
+func WithPinnedDNS(refresh time.Duration) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if opts.OnConnectAttempt != nil {
+			return fmt.Errorf("awsiotcore: WithPinnedDNS can't be combined with a connect attempt handler")
+		}
+		if opts.CustomOpenConnectionFn != nil {
+			return fmt.Errorf("awsiotcore: WithPinnedDNS can't be combined with WithoutSNI")
+		}
+
+		r := &pinnedResolver{
+			refresh: refresh,
+			lookup:  net.DefaultResolver.LookupHost,
+		}
+
+		// Resolve now so that the first connection doesn't have to. Failure isn't fatal; see above.
+		for _, broker := range opts.Servers {
+			r.resolve(context.Background(), broker.Hostname())
+		}
+
+		opts.SetCustomOpenConnectionFn(func(uri *url.URL, options mqtt.ClientOptions) (net.Conn, error) {
+			return r.dial(uri, options)
+		})
+		return nil
+	}
+}
+
+// dial opens a TLS connection to the first of the resolved addresses that accepts one.
+func (r *pinnedResolver) dial(uri *url.URL, options mqtt.ClientOptions) (net.Conn, error) {
+	switch uri.Scheme {
+	case "ssl", "tls", "mqtts", "tcps":
+	default:
+		return nil, fmt.Errorf("awsiotcore: pinned DNS does not support scheme %q", uri.Scheme)
+	}
+
+	dialer := options.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: options.ConnectTimeout}
+	}
+
+	addrs, err := r.resolve(context.Background(), uri.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	// Since the connection is to an IP, the server name used to verify the broker's cert must be set explicitly.
+	tlsConf := &tls.Config{}
+	if options.TLSConfig != nil {
+		tlsConf = options.TLSConfig.Clone()
+	}
+	if tlsConf.ServerName == "" {
+		tlsConf.ServerName = uri.Hostname()
+	}
+
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(addr, uri.Port()), tlsConf)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
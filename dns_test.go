@@ -0,0 +1,99 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPinnedResolver(t *testing.T) {
+	lookups := 0
+	var lookupErr error
+	r := &pinnedResolver{
+		refresh: time.Hour,
+		lookup: func(ctx context.Context, host string) ([]string, error) {
+			lookups++
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			return []string{"192.0.2.1"}, nil
+		},
+	}
+
+	want := []string{"192.0.2.1"}
+	for i := 0; i < 3; i++ {
+		got, err := r.resolve(context.Background(), "myendpoint")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("got %d lookups, want 1", lookups)
+	}
+
+	// Once stale, a failed lookup falls back to the cached addresses.
+	r.hosts["myendpoint"].resolvedAt = time.Now().Add(-2 * time.Hour)
+	lookupErr = errors.New("no network")
+	got, err := r.resolve(context.Background(), "myendpoint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if lookups != 2 {
+		t.Errorf("got %d lookups, want 2", lookups)
+	}
+
+	// With nothing cached, a failed lookup is an error.
+	r.hosts["myendpoint"].addrs = nil
+	if _, err := r.resolve(context.Background(), "myendpoint"); err == nil {
+		t.Errorf("got nil error, want non-nil")
+	}
+}
+
+func TestPinnedResolverPerHost(t *testing.T) {
+	var looked []string
+	r := &pinnedResolver{
+		refresh: time.Hour,
+		lookup: func(ctx context.Context, host string) ([]string, error) {
+			looked = append(looked, host)
+			return []string{"192.0.2.1"}, nil
+		},
+	}
+
+	for _, host := range []string{"primary", "standby", "primary", "standby"} {
+		if _, err := r.resolve(context.Background(), host); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if want := []string{"primary", "standby"}; !reflect.DeepEqual(looked, want) {
+		t.Errorf("got lookups %v, want %v", looked, want)
+	}
+}
+
+func TestWithPinnedDNSConnectAttemptHandler(t *testing.T) {
+	// The .invalid TLD is guaranteed never to resolve, so WithPinnedDNS's initial lookup fails fast.
+	d := &Device{Endpoint: "abc123-ats.iot.example.invalid", DeviceID: "foo"}
+
+	opts := testOpts(d)
+	if err := WithFallbackEndpoint("standby-ats.iot.us-east-1.amazonaws.com", 3)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WithPinnedDNS(time.Hour)(d, opts); err == nil {
+		t.Errorf("got nil error for WithPinnedDNS after WithFallbackEndpoint, want non-nil")
+	}
+
+	opts = testOpts(d)
+	if err := WithPinnedDNS(time.Hour)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WithFallbackEndpoint("standby-ats.iot.us-east-1.amazonaws.com", 3)(d, opts); err == nil {
+		t.Errorf("got nil error for WithFallbackEndpoint after WithPinnedDNS, want non-nil")
+	}
+}
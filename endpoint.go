@@ -0,0 +1,36 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ATSEndpoint returns the Amazon Trust Services (ATS) data endpoint for the given account-specific prefix and region,
+// i.e. <prefix>-ats.iot.<region>.amazonaws.com. The prefix is the part of the endpoint before "-ats" as shown in the
+// AWS IoT console or returned by aws iot describe-endpoint --endpoint-type iot:Data-ATS.
+func ATSEndpoint(prefix, region string) string {
+	return fmt.Sprintf("%s-ats.iot.%s.amazonaws.com", prefix, region)
+}
+
+// DataPlaneURL returns the URL to which a message may be published to topic over HTTPS, i.e.
+// https://<endpoint>:8443/topics/<topic>?qos=<qos>, with the topic URL-encoded as a single path segment.
+// See https://docs.aws.amazon.com/iot/latest/developerguide/http.html.
+func (d *Device) DataPlaneURL(topic string, qos int) (string, error) {
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return "", fmt.Errorf("awsiotcore: invalid publish topic %q", topic)
+	}
+	if qos < 0 || qos > 1 {
+		return "", fmt.Errorf("%w, got %d", ErrUnsupportedQoS, qos)
+	}
+
+	u := url.URL{
+		Scheme:   "https",
+		Host:     net.JoinHostPort(d.Endpoint, "8443"),
+		Path:     "/topics/" + topic,
+		RawPath:  "/topics/" + url.PathEscape(topic),
+		RawQuery: fmt.Sprintf("qos=%d", qos),
+	}
+	return u.String(), nil
+}
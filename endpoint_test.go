@@ -0,0 +1,34 @@
+package awsiotcore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestATSEndpoint(t *testing.T) {
+	got := ATSEndpoint("abc123", "us-west-2")
+	if want := "abc123-ats.iot.us-west-2.amazonaws.com"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if isLegacyEndpoint(got) {
+		t.Errorf("isLegacyEndpoint(%q) = true, want false", got)
+	}
+}
+
+func TestDataPlaneURL(t *testing.T) {
+	d := &Device{Endpoint: "abc123-ats.iot.us-west-2.amazonaws.com"}
+	got, err := d.DataPlaneURL("things/foo bar/telemetry", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "https://abc123-ats.iot.us-west-2.amazonaws.com:8443/topics/things%2Ffoo%20bar%2Ftelemetry?qos=1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := d.DataPlaneURL("things/+/telemetry", 0); err == nil {
+		t.Errorf("got nil error for topic with wildcard, want non-nil")
+	}
+	if _, err := d.DataPlaneURL("things/foo/telemetry", 2); !errors.Is(err, ErrUnsupportedQoS) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedQoS)
+	}
+}
@@ -0,0 +1,35 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"time"
+)
+
+// TelemetryEnvelope wraps a telemetry payload with fields that identify where and when it came from.
+type TelemetryEnvelope struct {
+	DeviceID string `json:"device_id"`
+	// Timestamp is when the message was published, in UTC.
+	Timestamp     time.Time   `json:"timestamp"`
+	SchemaVersion string      `json:"schema_version"`
+	Payload       interface{} `json:"payload"`
+}
+
+// WithTelemetryEnvelope returns a PublishOption that wraps the published value in a TelemetryEnvelope with the
+// given schema version. Wrapping in one place means that every device program formats the envelope, and in
+// particular the timestamp, the same way. The option needs to know the device ID, so it may only be used with
+// Device.PublishTelemetry.
+func WithTelemetryEnvelope(schemaVersion string) PublishOption {
+	return func(req *publishRequest) error {
+		if req.device == nil {
+			return fmt.Errorf("awsiotcore: WithTelemetryEnvelope may only be used with Device.PublishTelemetry")
+		}
+
+		req.value = TelemetryEnvelope{
+			DeviceID:      req.device.ID(),
+			Timestamp:     req.device.clockOrSystem().Now().UTC(),
+			SchemaVersion: schemaVersion,
+			Payload:       req.value,
+		}
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWithTelemetryEnvelope(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	before := time.Now()
+	if err := d.PublishTelemetry(client, reading{Temp: 18.5}, WithTelemetryEnvelope("2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+
+	var got struct {
+		DeviceID      string    `json:"device_id"`
+		Timestamp     time.Time `json:"timestamp"`
+		SchemaVersion string    `json:"schema_version"`
+		Payload       reading   `json:"payload"`
+	}
+	if err := json.Unmarshal(msgs[0].payload, &got); err != nil {
+		t.Fatalf("failed to parse payload: %v", err)
+	}
+
+	if got.DeviceID != "foo" || got.SchemaVersion != "2" || got.Payload.Temp != 18.5 {
+		t.Errorf("got %+v, want device ID foo, schema version 2, and temp 18.5", got)
+	}
+	if got.Timestamp.Before(before.Truncate(time.Second)) || got.Timestamp.Location() != time.UTC {
+		t.Errorf("got timestamp %v, want a UTC time after %v", got.Timestamp, before)
+	}
+}
+
+func TestWithTelemetryEnvelopeNoDevice(t *testing.T) {
+	client := newFakeClient()
+	if err := PublishJSON(client, "things/foo/telemetry", 1, false, reading{}, WithTelemetryEnvelope("2")); err == nil {
+		t.Errorf("got nil error, want non-nil")
+	}
+}
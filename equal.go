@@ -0,0 +1,39 @@
+package awsiotcore
+
+import (
+	"reflect"
+)
+
+// Equal reports whether d and other have the same configuration. Cert and key fields are compared by path, not by the
+// contents of the files they point to.
+func (d *Device) Equal(other *Device) bool {
+	if d == nil || other == nil {
+		return d == other
+	}
+	return len(d.Diff(other)) == 0
+}
+
+// Diff returns the names of the fields whose values differ between d and other, in the order they are declared in
+// Device. As with Equal, cert and key fields are compared by path. If exactly one of d and other is nil then all
+// field names are returned.
+func (d *Device) Diff(other *Device) []string {
+	if d == nil && other == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(Device{})
+	var diff []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if d == nil || other == nil ||
+			!reflect.DeepEqual(reflect.ValueOf(*d).Field(i).Interface(), reflect.ValueOf(*other).Field(i).Interface()) {
+			diff = append(diff, f.Name)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,80 @@
+package awsiotcore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	base := Device{
+		Endpoint:    "myendpoint",
+		DeviceID:    "foo",
+		CACerts:     "roots.pem",
+		CertPath:    "foo.x509",
+		PrivKeyPath: "foo.pem",
+	}
+
+	changed := base
+	changed.Endpoint = "otherendpoint"
+	changed.CertPath = "bar.x509"
+
+	cases := []struct {
+		name  string
+		a     *Device
+		b     *Device
+		want  []string
+		equal bool
+	}{
+		{
+			name:  "same",
+			a:     &base,
+			b:     &Device{Endpoint: "myendpoint", DeviceID: "foo", CACerts: "roots.pem", CertPath: "foo.x509", PrivKeyPath: "foo.pem"},
+			want:  nil,
+			equal: true,
+		},
+		{
+			name:  "different",
+			a:     &base,
+			b:     &changed,
+			want:  []string{"Endpoint", "CertPath"},
+			equal: false,
+		},
+		{
+			name:  "both_nil",
+			a:     nil,
+			b:     nil,
+			want:  nil,
+			equal: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := c.a.Diff(c.b)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Diff: got %v, want %v", got, c.want)
+			}
+
+			if eq := c.a.Equal(c.b); eq != c.equal {
+				t.Errorf("Equal: got %v, want %v", eq, c.equal)
+			}
+		})
+	}
+}
+
+func TestDiffNil(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	if d.Equal(nil) {
+		t.Errorf("Equal(nil): got true, want false")
+	}
+
+	exported := 0
+	for _, f := range reflect.VisibleFields(reflect.TypeOf(Device{})) {
+		if f.IsExported() {
+			exported++
+		}
+	}
+	if got := d.Diff(nil); len(got) != exported {
+		t.Errorf("Diff(nil): got %v, want all exported fields", got)
+	}
+}
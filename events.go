@@ -0,0 +1,90 @@
+package awsiotcore
+
+import (
+	"errors"
+	"sync/atomic"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// EventType is the type of a connection lifecycle Event.
+type EventType int
+
+const (
+	// EventConnected is sent the first time the client connects.
+	EventConnected EventType = iota
+	// EventReconnected is sent each time the client connects after the first.
+	EventReconnected
+	// EventDisconnected is sent when the connection is lost. The Event's Err says why.
+	EventDisconnected
+	// EventError is sent when an attempt to reconnect fails. paho doesn't report why, so the Event's Err is
+	// ErrReconnectFailed; the cause is logged to paho's ERROR logger.
+	EventError
+)
+
+// ErrReconnectFailed is the Err of an EventError.
+var ErrReconnectFailed = errors.New("awsiotcore: reconnect attempt failed")
+
+func (t EventType) String() string {
+	switch t {
+	case EventConnected:
+		return "Connected"
+	case EventReconnected:
+		return "Reconnected"
+	case EventDisconnected:
+		return "Disconnected"
+	case EventError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event is a connection lifecycle event.
+type Event struct {
+	Type EventType
+	Err  error
+}
+
+// WithEvents returns an option that sends connection lifecycle events on the given channel. It's an alternative to
+// setting OnConnect and ConnectionLost handlers that fits better into a select loop. Any handlers set by other
+// options are preserved.
+//
+// Events are sent without blocking so that the client's callbacks never stall. If the channel is not ready to
+// receive an event then the event is dropped, so give the channel a buffer if you can't always be receiving.
+//
+// A failed reconnect attempt is detected when paho starts the next one, so EventError is sent just before each
+// retry. Failures of the first connection aren't sent; they're reported by the token returned by Connect.
+func WithEvents(events chan<- Event) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		var connected atomic.Bool
+		// reconnecting is set while a reconnect attempt hasn't yet connected.
+		var reconnecting atomic.Bool
+
+		send := func(e Event) {
+			select {
+			case events <- e:
+			default:
+			}
+		}
+
+		addOnConnectHandler(opts, func(c mqtt.Client) {
+			reconnecting.Store(false)
+			if connected.Swap(true) {
+				send(Event{Type: EventReconnected})
+			} else {
+				send(Event{Type: EventConnected})
+			}
+		})
+		addConnectionLostHandler(opts, func(c mqtt.Client, err error) {
+			send(Event{Type: EventDisconnected, Err: err})
+		})
+		addReconnectingHandler(opts, func(c mqtt.Client, o *mqtt.ClientOptions) {
+			if reconnecting.Swap(true) {
+				send(Event{Type: EventError, Err: ErrReconnectFailed})
+			}
+		})
+
+		return nil
+	}
+}
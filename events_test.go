@@ -0,0 +1,45 @@
+package awsiotcore
+
+import (
+	"errors"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestWithEvents(t *testing.T) {
+	events := make(chan Event, 4)
+	opts := mqtt.NewClientOptions()
+	if err := WithEvents(events)(&Device{}, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lost := errors.New("lost")
+	opts.OnConnect(nil)
+	opts.OnConnectionLost(nil, lost)
+	// Two reconnect attempts, the first of which fails.
+	opts.OnReconnecting(nil, opts)
+	opts.OnReconnecting(nil, opts)
+	opts.OnConnect(nil)
+
+	want := []Event{
+		{Type: EventConnected},
+		{Type: EventDisconnected, Err: lost},
+		{Type: EventError, Err: ErrReconnectFailed},
+		{Type: EventReconnected},
+	}
+	for _, w := range want {
+		got := <-events
+		if got != w {
+			t.Errorf("got %v, want %v", got, w)
+		}
+	}
+
+	// Nothing is receiving on an unbuffered channel, so the event must be dropped rather than block.
+	full := make(chan Event)
+	opts = mqtt.NewClientOptions()
+	if err := WithEvents(full)(&Device{}, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts.OnConnect(nil)
+}
@@ -1,9 +1,14 @@
 package awsiotcore_test
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/mtraver/awsiotcore"
 )
 
@@ -33,3 +38,24 @@ func Example() {
 	client.Disconnect(250)
 	time.Sleep(500 * time.Millisecond)
 }
+
+func ExampleRun() {
+	d := &awsiotcore.Device{
+		Endpoint:    "my-endpoint",
+		DeviceID:    "my-device",
+		CACerts:     "roots.pem",
+		CertPath:    "my-device.x509",
+		PrivKeyPath: "my-device.pem",
+	}
+
+	// Run until the process receives SIGTERM or SIGINT.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	err := awsiotcore.Run(ctx, d, func(client mqtt.Client) error {
+		return d.PublishTelemetry(client, map[string]float64{"temp": 18.0})
+	})
+	if err != nil {
+		log.Fatalf("Failed to run: %v", err)
+	}
+}
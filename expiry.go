@@ -0,0 +1,46 @@
+package awsiotcore
+
+import (
+	"context"
+	"time"
+)
+
+// certExpiryRecheck is the longest WatchCertExpiry sleeps before reading the device's cert again, so that it notices
+// a rotated cert.
+const certExpiryRecheck = time.Hour
+
+// WatchCertExpiry calls onExpiring once the device's cert is within warnBefore of expiring, so that the program can
+// stop taking on new work, flush pending publishes, and disconnect before the broker rejects the cert. It blocks
+// until ctx is done, then returns ctx.Err(), so run it in its own goroutine.
+//
+// The cert is read again at least hourly, so if it's replaced, e.g. by ManagedClient.RotateCertificate, the new one
+// is watched. onExpiring is called at most once per cert. An error reading the cert stops WatchCertExpiry and is
+// returned.
+func (d *Device) WatchCertExpiry(ctx context.Context, warnBefore time.Duration, onExpiring func()) error {
+	clock := d.clockOrSystem()
+	notified := ""
+	for {
+		cert, err := readCert(d.CertPath)
+		if err != nil {
+			return err
+		}
+
+		id := string(cert.Raw)
+		wait := cert.NotAfter.Add(-warnBefore).Sub(clock.Now())
+		if wait <= 0 && id != notified {
+			notified = id
+			onExpiring()
+		}
+		if wait <= 0 || wait > certExpiryRecheck {
+			wait = certExpiryRecheck
+		}
+
+		t := clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C():
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchCertExpiry(t *testing.T) {
+	tc := writeTestCert(t, "foo", time.Now().Add(-time.Hour), time.Now().Add(30*time.Minute))
+	d := &Device{DeviceID: "foo", CertPath: tc.certPath}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	expiring := make(chan struct{}, 2)
+	done := make(chan error)
+	go func() {
+		done <- d.WatchCertExpiry(ctx, time.Hour, func() { expiring <- struct{}{} })
+	}()
+
+	select {
+	case <-expiring:
+	case <-time.After(time.Second):
+		t.Fatalf("onExpiring not called for a cert expiring within warnBefore")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if len(expiring) != 0 {
+		t.Errorf("onExpiring called more than once")
+	}
+}
+
+func TestWatchCertExpiryNotExpiring(t *testing.T) {
+	tc := writeTestCert(t, "foo", time.Now().Add(-time.Hour), time.Now().Add(48*time.Hour))
+	d := &Device{DeviceID: "foo", CertPath: tc.certPath}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	called := false
+	if err := d.WatchCertExpiry(ctx, time.Hour, func() { called = true }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if called {
+		t.Errorf("onExpiring called for a cert that isn't expiring")
+	}
+}
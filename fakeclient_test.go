@@ -0,0 +1,205 @@
+package awsiotcore
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// fakeToken is an mqtt.Token that is already complete.
+type fakeToken struct {
+	err error
+}
+
+func (t *fakeToken) Wait() bool                     { return true }
+func (t *fakeToken) WaitTimeout(time.Duration) bool { return true }
+func (t *fakeToken) Error() error                   { return t.err }
+
+func (t *fakeToken) Done() <-chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+// fakeSubscribeToken is a completed subscribe token whose Result reports the QoS granted for each filter, like
+// mqtt.SubscribeToken.
+type fakeSubscribeToken struct {
+	fakeToken
+	result map[string]byte
+}
+
+func (t *fakeSubscribeToken) Result() map[string]byte { return t.result }
+
+// pendingToken is an mqtt.Token that never completes.
+type pendingToken struct{}
+
+func (t pendingToken) Wait() bool                     { select {} }
+func (t pendingToken) WaitTimeout(time.Duration) bool { return false }
+func (t pendingToken) Done() <-chan struct{}          { return nil }
+func (t pendingToken) Error() error                   { return nil }
+
+// fakeMessage is an mqtt.Message.
+type fakeMessage struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+	acked    bool
+}
+
+func (m *fakeMessage) Duplicate() bool   { return false }
+func (m *fakeMessage) Qos() byte         { return m.qos }
+func (m *fakeMessage) Retained() bool    { return m.retained }
+func (m *fakeMessage) Topic() string     { return m.topic }
+func (m *fakeMessage) MessageID() uint16 { return 0 }
+func (m *fakeMessage) Payload() []byte   { return m.payload }
+func (m *fakeMessage) Ack()              { m.acked = true }
+
+// fakeClient is an in-memory mqtt.Client. Published messages are recorded, and if respond is set it's called for
+// each publish, which lets tests play the part of the broker by calling deliver.
+type fakeClient struct {
+	mu        sync.Mutex
+	connected bool
+	published []*fakeMessage
+	subs      map[string]mqtt.MessageHandler
+	respond   func(c *fakeClient, m *fakeMessage)
+
+	// publishErr, if set, is returned by the token of every publish.
+	publishErr error
+	// publishHangs, if set, makes publish tokens never complete.
+	publishHangs bool
+	// connect, if set, is called by Connect and its result is returned by the token.
+	connect func() error
+	// granted, if set, overrides the QoS granted in the SUBACK for the given filters, e.g. 0x80 to reject them.
+	granted map[string]byte
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		connected: true,
+		subs:      make(map[string]mqtt.MessageHandler),
+	}
+}
+
+func (c *fakeClient) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+func (c *fakeClient) IsConnectionOpen() bool {
+	return c.IsConnected()
+}
+
+func (c *fakeClient) Connect() mqtt.Token {
+	if c.connect != nil {
+		if err := c.connect(); err != nil {
+			return &fakeToken{err: err}
+		}
+	}
+	c.mu.Lock()
+	c.connected = true
+	c.mu.Unlock()
+	return &fakeToken{}
+}
+
+func (c *fakeClient) Disconnect(quiesce uint) {
+	c.mu.Lock()
+	c.connected = false
+	c.mu.Unlock()
+}
+
+func (c *fakeClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	var b []byte
+	switch p := payload.(type) {
+	case []byte:
+		b = p
+	case string:
+		b = []byte(p)
+	}
+
+	m := &fakeMessage{topic: topic, qos: qos, retained: retained, payload: b}
+	c.mu.Lock()
+	c.published = append(c.published, m)
+	respond := c.respond
+	c.mu.Unlock()
+
+	if respond != nil {
+		go respond(c, m)
+	}
+	if c.publishHangs {
+		return pendingToken{}
+	}
+	return &fakeToken{err: c.publishErr}
+}
+
+func (c *fakeClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs[topic] = callback
+
+	// Like paho's Subscribe, report the result under the filter without any $share/<group>/ or $queue/ prefix.
+	key := topic
+	if strings.HasPrefix(key, "$share/") {
+		key = strings.Join(strings.Split(key, "/")[2:], "/")
+	}
+	key = strings.TrimPrefix(key, "$queue/")
+	return c.suback(map[string]byte{key: qos})
+}
+
+func (c *fakeClient) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic := range filters {
+		c.subs[topic] = callback
+	}
+	return c.suback(filters)
+}
+
+// suback returns a subscribe token that grants the requested QoS for each filter, unless granted overrides it.
+// c.mu must be held.
+func (c *fakeClient) suback(requested map[string]byte) mqtt.Token {
+	result := make(map[string]byte, len(requested))
+	for topic, qos := range requested {
+		if g, ok := c.granted[topic]; ok {
+			qos = g
+		}
+		result[topic] = qos
+	}
+	return &fakeSubscribeToken{result: result}
+}
+
+func (c *fakeClient) Unsubscribe(topics ...string) mqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, topic := range topics {
+		delete(c.subs, topic)
+	}
+	return &fakeToken{}
+}
+
+func (c *fakeClient) AddRoute(topic string, callback mqtt.MessageHandler) {}
+
+func (c *fakeClient) OptionsReader() mqtt.ClientOptionsReader {
+	return mqtt.ClientOptionsReader{}
+}
+
+// deliver sends a message to the handler subscribed to exactly the given topic, if any.
+func (c *fakeClient) deliver(topic string, payload []byte) {
+	c.mu.Lock()
+	h, ok := c.subs[topic]
+	c.mu.Unlock()
+
+	if ok {
+		h(c, &fakeMessage{topic: topic, payload: payload})
+	}
+}
+
+// messages returns the messages published so far.
+func (c *fakeClient) messages() []*fakeMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*fakeMessage(nil), c.published...)
+}
@@ -0,0 +1,90 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync/atomic"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// WithFallbackEndpoint returns an option that switches the client to connect to endpoint, e.g. that of a standby
+// AWS account, after after connection attempts in a row have been refused by the broker. The device's cert must be
+// registered in the fallback account too.
+//
+// paho doesn't report why an attempt failed, so an attempt counts as refused if it failed after the TLS handshake
+// reached the point where the broker asks for the device's cert, i.e. the broker was reachable and its cert verified
+// but it didn't accept the device, e.g. because its cert isn't registered or is inactive, or its policy doesn't allow
+// it to connect. Attempts that fail earlier, e.g. because the network is down, reset the count, as does a successful
+// connection, so network outages and brief drops like those caused by another client connecting with the same
+// client ID don't cause a switch. Both the first connection and reconnections count.
+//
+// The switch is made on the attempt after the last refused one, keeps the broker's port, and also changes the TLS
+// server name. It lasts for the life of the client. The Device's Endpoint is left unchanged, since the Device may be
+// shared with other clients.
+func WithFallbackEndpoint(endpoint string, after int) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if endpoint == "" {
+			return fmt.Errorf("awsiotcore: fallback endpoint must not be empty")
+		}
+		if after < 1 {
+			return fmt.Errorf("awsiotcore: fallback endpoint failure count must be at least 1, got %d", after)
+		}
+
+		var (
+			// pending is set while an attempt hasn't yet connected, and certRequested once the broker has asked for
+			// the device's cert during it.
+			pending       atomic.Bool
+			certRequested atomic.Bool
+			refused       atomic.Int32
+			switched      atomic.Bool
+		)
+
+		addOnConnectHandler(opts, func(c mqtt.Client) {
+			pending.Store(false)
+			refused.Store(0)
+		})
+
+		return WithConnectAttemptHandler(func(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
+			// An attempt starting while the previous one is still pending means the previous one failed.
+			if pending.Swap(true) {
+				if !certRequested.Load() {
+					refused.Store(0)
+				} else if int(refused.Add(1)) >= after && !switched.Swap(true) {
+					mqtt.WARN.Printf("awsiotcore: broker refused %d connection attempts in a row, switching to fallback endpoint %s", after, endpoint)
+				}
+			}
+			certRequested.Store(false)
+
+			if tlsCfg == nil {
+				return nil
+			}
+			tlsCfg = tlsCfg.Clone()
+			getCert := tlsCfg.GetClientCertificate
+			certs := tlsCfg.Certificates
+			tlsCfg.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				certRequested.Store(true)
+				if getCert != nil {
+					return getCert(info)
+				}
+				if len(certs) == 0 {
+					return &tls.Certificate{}, nil
+				}
+				return &certs[0], nil
+			}
+
+			if !switched.Load() {
+				return tlsCfg
+			}
+
+			// paho keeps using the URL it passes, so changing it moves this and later attempts to the fallback.
+			broker.Host = net.JoinHostPort(endpoint, broker.Port())
+			if tlsCfg.ServerName != "" {
+				tlsCfg.ServerName = endpoint
+			}
+			return tlsCfg
+		})(d, opts)
+	}
+}
@@ -0,0 +1,69 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestWithFallbackEndpoint(t *testing.T) {
+	d := &Device{Endpoint: "primary-ats.iot.us-west-2.amazonaws.com", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithFallbackEndpoint("standby-ats.iot.us-east-1.amazonaws.com", 2)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const (
+		primary = "primary-ats.iot.us-west-2.amazonaws.com:8883 primary-ats.iot.us-west-2.amazonaws.com"
+		standby = "standby-ats.iot.us-east-1.amazonaws.com:8883 standby-ats.iot.us-east-1.amazonaws.com"
+	)
+	broker := opts.Servers[0]
+	// attempt starts a connection attempt and returns where it connects. If refused is set, the attempt gets as far
+	// as the broker asking for the device's cert, as it does when the broker goes on to refuse the device.
+	attempt := func(refused bool) string {
+		tlsCfg := opts.OnConnectAttempt(broker, opts.TLSConfig)
+		if refused {
+			if _, err := tlsCfg.GetClientCertificate(&tls.CertificateRequestInfo{}); err != nil {
+				t.Fatalf("unexpected error from GetClientCertificate: %v", err)
+			}
+		}
+		return broker.Host + " " + tlsCfg.ServerName
+	}
+
+	steps := []struct {
+		name    string
+		refused bool
+		connect bool
+		want    string
+	}{
+		{"first attempt", true, false, primary},
+		{"after one refusal", false, false, primary},
+		{"after a network failure", true, false, primary},
+		{"after one refusal since a network failure", true, true, primary},
+		{"after connecting", true, false, primary},
+		{"after one refusal since connecting", true, false, primary},
+		{"after two refusals in a row", false, false, standby},
+		{"after switching", false, false, standby},
+	}
+	for _, s := range steps {
+		if got := attempt(s.refused); got != s.want {
+			t.Errorf("%s: got %q, want %q", s.name, got, s.want)
+		}
+		if s.connect {
+			opts.OnConnect(newFakeClient())
+		}
+	}
+
+	if opts.TLSConfig.ServerName != d.Endpoint || d.Endpoint != "primary-ats.iot.us-west-2.amazonaws.com" {
+		t.Errorf("original TLS config or device was modified")
+	}
+}
+
+func TestWithFallbackEndpointInvalid(t *testing.T) {
+	d := &Device{Endpoint: "primary-ats.iot.us-west-2.amazonaws.com", DeviceID: "foo"}
+	if err := WithFallbackEndpoint("", 3)(d, testOpts(d)); err == nil {
+		t.Errorf("got nil error for empty endpoint, want non-nil")
+	}
+	if err := WithFallbackEndpoint("standby-ats.iot.us-east-1.amazonaws.com", 0)(d, testOpts(d)); err == nil {
+		t.Errorf("got nil error for zero failure count, want non-nil")
+	}
+}
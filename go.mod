@@ -2,10 +2,14 @@ module github.com/mtraver/awsiotcore
 
 go 1.20
 
-require github.com/eclipse/paho.mqtt.golang v1.4.2
+require (
+	github.com/eclipse/paho.mqtt.golang v1.4.2
+	github.com/fxamacker/cbor/v2 v2.5.0
+)
 
 require (
 	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 	golang.org/x/net v0.10.0 // indirect
 	golang.org/x/sync v0.2.0 // indirect
 )
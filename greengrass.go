@@ -0,0 +1,69 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ConnectivityInfo is an address at which a Greengrass core device may be reached, as returned by Greengrass
+// discovery. See https://docs.aws.amazon.com/greengrass/v2/developerguide/greengrass-discover-api.html.
+type ConnectivityInfo struct {
+	ID          string `json:"Id"`
+	HostAddress string `json:"HostAddress"`
+	PortNumber  int    `json:"PortNumber"`
+	Metadata    string `json:"Metadata"`
+}
+
+// Broker returns the MQTT broker at the address.
+func (c ConnectivityInfo) Broker() MQTTBroker {
+	return MQTTBroker{
+		Scheme: "ssl",
+		Host:   c.HostAddress,
+		Port:   c.PortNumber,
+	}
+}
+
+// NewGreengrassClient is like NewClient but creates a client that connects to a Greengrass core device's local MQTT
+// broker rather than to AWS IoT Core. groupCA is the PEM-encoded CA cert returned by Greengrass discovery; the client
+// trusts it rather than the Amazon roots in CACerts. The core's cert is verified against the host address being
+// connected to. The device's Endpoint and CACerts are not used.
+func (d *Device) NewGreengrassClient(coreInfo ConnectivityInfo, groupCA []byte, options ...func(*Device, *mqtt.ClientOptions) error) (mqtt.Client, error) {
+	if err := ValidateDeviceID(d.DeviceID); err != nil {
+		return nil, err
+	}
+
+	certpool := x509.NewCertPool()
+	if !certpool.AppendCertsFromPEM(groupCA) {
+		return nil, fmt.Errorf("awsiotcore: no certs were parsed from given group CA")
+	}
+
+	cert, err := loadX509KeyPair(d.CertPath, d.PrivKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to load x509 key pair: %w", err)
+	}
+
+	tlsConf := &tls.Config{
+		RootCAs:      certpool,
+		Certificates: []tls.Certificate{cert},
+		ServerName:   coreInfo.HostAddress,
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	broker := coreInfo.Broker()
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(broker.URL())
+	opts.SetClientID(d.DeviceID)
+	opts.SetTLSConfig(tlsConf)
+
+	for _, option := range options {
+		if err := option(d, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return mqtt.NewClient(opts), nil
+}
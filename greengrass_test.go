@@ -0,0 +1,42 @@
+package awsiotcore
+
+import (
+	"encoding/pem"
+	"os"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestNewGreengrassClient(t *testing.T) {
+	d := testDevice(t)
+	d.Endpoint = ""
+	d.CACerts = ""
+
+	groupCA, err := os.ReadFile(d.CertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := ConnectivityInfo{ID: "core", HostAddress: "192.168.1.10", PortNumber: 8883}
+
+	var opts *mqtt.ClientOptions
+	_, err = d.NewGreengrassClient(core, groupCA, func(d *Device, o *mqtt.ClientOptions) error {
+		opts = o
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := opts.Servers[0].String(), "ssl://192.168.1.10:8883"; got != want {
+		t.Errorf("got broker %q, want %q", got, want)
+	}
+	if opts.TLSConfig.ServerName != core.HostAddress {
+		t.Errorf("got ServerName %q, want %q", opts.TLSConfig.ServerName, core.HostAddress)
+	}
+
+	notPEM := pem.EncodeToMemory(&pem.Block{Type: "NOT A CERT", Bytes: []byte("x")})
+	if _, err := d.NewGreengrassClient(core, notPEM); err == nil {
+		t.Errorf("got nil error for bad group CA, want non-nil")
+	}
+}
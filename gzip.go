@@ -0,0 +1,106 @@
+package awsiotcore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// gzipMagic is the first two bytes of every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// MaxDecompressedSize is the largest payload, in bytes, that DecompressHandler and the gzip codec decompress a
+// message to. Larger payloads are rejected so that a small hostile message can't expand to gigabytes in memory. It's
+// 16 times AWS IoT's 128 KB message size limit, which leaves room for highly compressible payloads like JSON.
+const MaxDecompressedSize = 16 * 128 * 1024
+
+// gzipCodec wraps a Codec, gzipping what it marshals and gunzipping what it unmarshals.
+type gzipCodec struct {
+	inner Codec
+}
+
+func (c gzipCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c gzipCodec) Unmarshal(data []byte, v interface{}) error {
+	b, err := gunzip(data)
+	if err != nil {
+		return err
+	}
+	return c.inner.Unmarshal(b, v)
+}
+
+// gunzip decompresses data, returning an error if the result would be larger than MaxDecompressedSize.
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Read one byte past the limit to tell a payload of exactly the limit from a larger one.
+	b, err := io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxDecompressedSize {
+		return nil, fmt.Errorf("awsiotcore: decompressed payload is larger than %d bytes", MaxDecompressedSize)
+	}
+	return b, nil
+}
+
+// WithGzip returns a PublishOption that gzips the encoded payload. Use DecompressHandler on the receiving side.
+func WithGzip() PublishOption {
+	return func(req *publishRequest) error {
+		req.codec = gzipCodec{inner: req.codec}
+		return nil
+	}
+}
+
+// payloadMessage is an mqtt.Message with its payload replaced.
+type payloadMessage struct {
+	mqtt.Message
+	payload []byte
+}
+
+func (m *payloadMessage) Payload() []byte {
+	return m.payload
+}
+
+// DecompressHandler returns a message handler that gunzips the payloads of gzipped messages before passing them to
+// inner. Messages whose payloads don't start with the gzip magic bytes are passed through unchanged, so it's safe to
+// use on topics that carry a mix of compressed and uncompressed messages. Messages that look gzipped but can't be
+// decompressed, or that decompress to more than MaxDecompressedSize bytes, are dropped and logged to paho's ERROR
+// logger.
+func DecompressHandler(inner mqtt.MessageHandler) mqtt.MessageHandler {
+	return func(c mqtt.Client, m mqtt.Message) {
+		if !bytes.HasPrefix(m.Payload(), gzipMagic) {
+			inner(c, m)
+			return
+		}
+
+		b, err := gunzip(m.Payload())
+		if err != nil {
+			mqtt.ERROR.Printf("awsiotcore: failed to decompress message on %s: %v", m.Topic(), err)
+			return
+		}
+		inner(c, &payloadMessage{Message: m, payload: b})
+	}
+}
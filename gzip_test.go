@@ -0,0 +1,76 @@
+package awsiotcore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestGzipRoundTrip(t *testing.T) {
+	client := newFakeClient()
+	want := reading{Temp: 18.5}
+	if err := PublishJSON(client, "things/foo/telemetry", 1, false, want, WithGzip()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	published := client.messages()[0]
+	if published.payload[0] != 0x1f || published.payload[1] != 0x8b {
+		t.Fatalf("payload is not gzipped: %q", published.payload)
+	}
+
+	var got reading
+	var calls int
+	h := DecompressHandler(func(c mqtt.Client, m mqtt.Message) {
+		calls++
+		if err := json.Unmarshal(m.Payload(), &got); err != nil {
+			t.Errorf("failed to parse decompressed payload: %v", err)
+		}
+		if m.Topic() != "things/foo/telemetry" {
+			t.Errorf("got topic %q, want %q", m.Topic(), "things/foo/telemetry")
+		}
+	})
+
+	h(client, published)
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Uncompressed payloads pass through.
+	h(client, &fakeMessage{topic: "things/foo/telemetry", payload: []byte(`{"temp": 18.5}`)})
+
+	// Corrupt gzip payloads are dropped.
+	h(client, &fakeMessage{topic: "things/foo/telemetry", payload: []byte{0x1f, 0x8b, 0x00}})
+
+	if calls != 2 {
+		t.Errorf("inner handler called %d times, want 2", calls)
+	}
+}
+
+func TestGunzipLimit(t *testing.T) {
+	compress := func(n int) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(make([]byte, n))
+		w.Close()
+		return buf.Bytes()
+	}
+
+	if b, err := gunzip(compress(MaxDecompressedSize)); err != nil || len(b) != MaxDecompressedSize {
+		t.Errorf("got %d bytes and error %v for a payload of the maximum size, want %d bytes", len(b), err, MaxDecompressedSize)
+	}
+
+	bomb := compress(MaxDecompressedSize + 1)
+	if _, err := gunzip(bomb); err == nil {
+		t.Errorf("got nil error for a payload over the maximum size, want non-nil")
+	}
+
+	called := false
+	h := DecompressHandler(func(c mqtt.Client, m mqtt.Message) { called = true })
+	h(newFakeClient(), &fakeMessage{topic: "things/foo/telemetry", payload: bomb})
+	if called {
+		t.Errorf("inner handler called for a payload over the maximum size")
+	}
+}
@@ -0,0 +1,55 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// HeartbeatTopic returns the MQTT topic to which the device publishes heartbeats with StartHeartbeat.
+func (d *Device) HeartbeatTopic() string {
+	return d.bridgeTopic(fmt.Sprintf("things/%v/heartbeat", d.DeviceID))
+}
+
+// StartHeartbeat publishes the payload returned by payloadFn to the device's heartbeat topic every interval, at QoS
+// 0 or the QoS TopicQoS gives for the topic, until the returned stop function is called. stop waits for any publish
+// in progress to finish and may be called more than once.
+//
+// Heartbeats that fall due while the client's connection is down are skipped rather than queued, so that a device
+// coming back online doesn't send a burst of stale heartbeats; publishing resumes once the client reconnects. Other
+// publish failures are logged to paho's WARN logger. Each publish waits at most interval.
+func (d *Device) StartHeartbeat(client mqtt.Client, interval time.Duration, payloadFn func() []byte) (stop func()) {
+	topic := d.HeartbeatTopic()
+	qos := d.topicQoS(topic, 0)
+	clock := d.clockOrSystem()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			t := clock.NewTimer(interval)
+			select {
+			case <-done:
+				t.Stop()
+				return
+			case <-t.C():
+			}
+
+			if !client.IsConnectionOpen() {
+				continue
+			}
+			if err := waitToken(client.Publish(topic, qos, false, payloadFn()), interval); err != nil {
+				mqtt.WARN.Printf("awsiotcore: failed to publish heartbeat to %s: %v", topic, err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
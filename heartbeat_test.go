@@ -0,0 +1,43 @@
+package awsiotcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartHeartbeat(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	d := &Device{DeviceID: "foo", clock: clock}
+	client := newFakeClient()
+
+	beats := 0
+	stop := d.StartHeartbeat(client, time.Minute, func() []byte {
+		beats++
+		return []byte("alive")
+	})
+
+	// Each advance fires the pending timer; waiting for the next timer means the heartbeat has been handled.
+	tick := func() {
+		<-clock.timerMade
+		clock.advance(time.Minute)
+	}
+	tick()
+	tick()
+	client.Disconnect(0)
+	tick()
+	client.Connect()
+	tick()
+	<-clock.timerMade
+	stop()
+	stop()
+
+	msgs := client.messages()
+	if len(msgs) != 3 || beats != 3 {
+		t.Fatalf("got %d messages from %d payloads, want 3 heartbeats with one skipped while disconnected", len(msgs), beats)
+	}
+	for _, m := range msgs {
+		if m.topic != "things/foo/heartbeat" || string(m.payload) != "alive" || m.qos != 0 {
+			t.Errorf("got message on %q with payload %q at QoS %d, want things/foo/heartbeat, alive, 0", m.topic, m.payload, m.qos)
+		}
+	}
+}
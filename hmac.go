@@ -0,0 +1,125 @@
+package awsiotcore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrBadSignature is returned by VerifyPayload and VerifyJSON when a payload's signature doesn't match its contents.
+var ErrBadSignature = errors.New("awsiotcore: payload signature is invalid")
+
+// SignPayload returns payload followed by its HMAC-SHA256 under key, so that a downstream system that shares the
+// key can check that the payload wasn't altered, e.g. by a misconfigured rule. The signature is the last 32 bytes
+// of the result. Use VerifyPayload or VerifyHandler to check and remove it.
+//
+// Appending raw bytes makes a JSON payload invalid JSON, so AWS IoT rules can no longer select its fields. It only
+// suits binary payloads; sign JSON payloads with SignJSON instead.
+func SignPayload(key []byte, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	signed := make([]byte, 0, len(payload)+sha256.Size)
+	signed = append(signed, payload...)
+	return mac.Sum(signed)
+}
+
+// VerifyPayload checks the signature of a payload signed by SignPayload and returns the payload without it. If the
+// signature doesn't match, the returned error matches ErrBadSignature with errors.Is.
+func VerifyPayload(key []byte, signed []byte) ([]byte, error) {
+	if len(signed) < sha256.Size {
+		return nil, ErrBadSignature
+	}
+
+	payload, sig := signed[:len(signed)-sha256.Size], signed[len(signed)-sha256.Size:]
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrBadSignature
+	}
+	return payload, nil
+}
+
+// signedJSON is the envelope in which SignJSON wraps a payload.
+type signedJSON struct {
+	Payload   json.RawMessage `json:"payload"`
+	Signature string          `json:"signature"`
+}
+
+// SignJSON is like SignPayload for JSON payloads. It returns a JSON object of the form
+//
+//	{"payload": <payload>, "signature": "<hex HMAC-SHA256 of payload under key>"}
+//
+// which is still JSON, so AWS IoT rules can select the payload's fields, e.g. SELECT payload.temp FROM .... The
+// signature covers payload's bytes as they appear in the envelope, i.e. without leading and trailing whitespace such
+// as the newline written by json.Encoder. Use VerifyJSON or VerifyJSONHandler to check and remove it.
+func SignJSON(key []byte, payload []byte) ([]byte, error) {
+	if !json.Valid(payload) {
+		return nil, fmt.Errorf("awsiotcore: payload to sign is not valid JSON")
+	}
+	// VerifyJSON decodes the payload with encoding/json, which drops surrounding whitespace.
+	payload = bytes.TrimSpace(payload)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+
+	// Build the envelope by hand: json.Marshal would compact a json.RawMessage, changing the bytes that were signed.
+	var b bytes.Buffer
+	b.WriteString(`{"payload":`)
+	b.Write(payload)
+	b.WriteString(`,"signature":"`)
+	b.WriteString(hex.EncodeToString(mac.Sum(nil)))
+	b.WriteString(`"}`)
+	return b.Bytes(), nil
+}
+
+// VerifyJSON checks the signature of a payload signed by SignJSON and returns the payload without the envelope. If
+// the signature doesn't match, the returned error matches ErrBadSignature with errors.Is.
+func VerifyJSON(key []byte, signed []byte) ([]byte, error) {
+	var env signedJSON
+	if err := json.Unmarshal(signed, &env); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse signed payload: %w", err)
+	}
+	sig, err := hex.DecodeString(env.Signature)
+	if err != nil {
+		return nil, ErrBadSignature
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(env.Payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrBadSignature
+	}
+	return env.Payload, nil
+}
+
+// VerifyHandler returns a handler that verifies the signature of each message, as signed by SignPayload, and passes
+// the message to inner with the signature removed. Messages whose signatures don't match are dropped and logged to
+// paho's ERROR logger.
+func VerifyHandler(key []byte, inner mqtt.MessageHandler) mqtt.MessageHandler {
+	return func(c mqtt.Client, m mqtt.Message) {
+		b, err := VerifyPayload(key, m.Payload())
+		if err != nil {
+			mqtt.ERROR.Printf("awsiotcore: dropping message on %s: %v", m.Topic(), err)
+			return
+		}
+		inner(c, &payloadMessage{Message: m, payload: b})
+	}
+}
+
+// VerifyJSONHandler is like VerifyHandler for messages signed by SignJSON.
+func VerifyJSONHandler(key []byte, inner mqtt.MessageHandler) mqtt.MessageHandler {
+	return func(c mqtt.Client, m mqtt.Message) {
+		b, err := VerifyJSON(key, m.Payload())
+		if err != nil {
+			mqtt.ERROR.Printf("awsiotcore: dropping message on %s: %v", m.Topic(), err)
+			return
+		}
+		inner(c, &payloadMessage{Message: m, payload: b})
+	}
+}
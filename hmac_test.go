@@ -0,0 +1,134 @@
+package awsiotcore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestSignPayload(t *testing.T) {
+	key := []byte("secret")
+	payload := []byte(`{"temp":18.5}`)
+	signed := SignPayload(key, payload)
+	if len(signed) != len(payload)+32 {
+		t.Errorf("got %d bytes, want %d", len(signed), len(payload)+32)
+	}
+
+	got, err := VerifyPayload(key, signed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("got %q, want %q", got, payload)
+	}
+
+	signed[0] = '['
+	if _, err := VerifyPayload(key, signed); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v for tampered payload, want %v", err, ErrBadSignature)
+	}
+	if _, err := VerifyPayload([]byte("other"), SignPayload(key, payload)); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v for wrong key, want %v", err, ErrBadSignature)
+	}
+	if _, err := VerifyPayload(key, []byte("short")); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v for short payload, want %v", err, ErrBadSignature)
+	}
+}
+
+func TestVerifyHandler(t *testing.T) {
+	key := []byte("secret")
+	var got []string
+	h := VerifyHandler(key, func(c mqtt.Client, m mqtt.Message) {
+		got = append(got, string(m.Payload()))
+	})
+
+	client := newFakeClient()
+	h(client, &fakeMessage{topic: "things/foo/telemetry", payload: SignPayload(key, []byte("good"))})
+	h(client, &fakeMessage{topic: "things/foo/telemetry", payload: SignPayload([]byte("other"), []byte("bad"))})
+
+	if len(got) != 1 || got[0] != "good" {
+		t.Errorf("got payloads %q, want [good]", got)
+	}
+}
+
+func TestSignJSON(t *testing.T) {
+	key := []byte("secret")
+	payload := []byte(`{ "temp": 18.5 }`)
+	signed, err := SignJSON(key, payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var env struct {
+		Payload struct {
+			Temp float64 `json:"temp"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(signed, &env); err != nil {
+		t.Fatalf("signed payload is not JSON: %v", err)
+	}
+	if env.Payload.Temp != 18.5 {
+		t.Errorf("got temp %v, want 18.5", env.Payload.Temp)
+	}
+
+	got, err := VerifyJSON(key, signed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("got %q, want %q", got, payload)
+	}
+
+	tampered := bytes.Replace(signed, []byte("18.5"), []byte("99.5"), 1)
+	if _, err := VerifyJSON(key, tampered); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v for tampered payload, want %v", err, ErrBadSignature)
+	}
+	if _, err := VerifyJSON([]byte("other"), signed); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("got error %v for wrong key, want %v", err, ErrBadSignature)
+	}
+	if _, err := VerifyJSON(key, []byte("not json")); err == nil {
+		t.Error("expected error for non-JSON payload")
+	}
+	if _, err := SignJSON(key, []byte("not json")); err == nil {
+		t.Error("expected error signing non-JSON payload")
+	}
+}
+
+func TestSignJSONEncoderOutput(t *testing.T) {
+	key := []byte("secret")
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]float64{"temp": 18.5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	signed, err := SignJSON(key, buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := VerifyJSON(key, signed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"temp":18.5}`; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVerifyJSONHandler(t *testing.T) {
+	key := []byte("secret")
+	var got []string
+	h := VerifyJSONHandler(key, func(c mqtt.Client, m mqtt.Message) {
+		got = append(got, string(m.Payload()))
+	})
+
+	good, _ := SignJSON(key, []byte(`"good"`))
+	bad, _ := SignJSON([]byte("other"), []byte(`"bad"`))
+	client := newFakeClient()
+	h(client, &fakeMessage{topic: "things/foo/telemetry", payload: good})
+	h(client, &fakeMessage{topic: "things/foo/telemetry", payload: bad})
+
+	if len(got) != 1 || got[0] != `"good"` {
+		t.Errorf("got payloads %q, want [\"good\"]", got)
+	}
+}
@@ -0,0 +1,90 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// JobTopic returns the topic for the given operation on the given job execution, e.g. "get" or "update/accepted".
+// See https://docs.aws.amazon.com/iot/latest/developerguide/jobs-mqtt-api.html.
+func (d *Device) JobTopic(jobID, operation string) string {
+	return d.bridgeTopic(fmt.Sprintf("$aws/things/%s/jobs/%s/%s", d.ID(), jobID, operation))
+}
+
+// JobExecutionsFilter returns a topic filter that matches the topics of all of the device's job executions, i.e.
+// $aws/things/<thing>/jobs/+/#, such as JobTopic(jobID, "update/accepted"). Note that it also matches the jobs
+// topics that aren't specific to a job, e.g. JobNotifyTopic; JobIDFromTopic returns an error for those.
+func (d *Device) JobExecutionsFilter() string {
+	return d.JobTopic("+", "#")
+}
+
+// JobNotifyTopic returns the topic on which AWS IoT publishes the device's list of pending job executions whenever
+// it changes.
+func (d *Device) JobNotifyTopic() string {
+	return d.bridgeTopic(fmt.Sprintf("$aws/things/%s/jobs/notify", d.ID()))
+}
+
+// JobNotifyNextTopic returns the topic on which AWS IoT publishes the device's next pending job execution whenever
+// it changes.
+func (d *Device) JobNotifyNextTopic() string {
+	return d.bridgeTopic(fmt.Sprintf("$aws/things/%s/jobs/notify-next", d.ID()))
+}
+
+// jobsNonJobLevels are the levels after $aws/things/<thing>/jobs/ that name an API rather than a job.
+var jobsNonJobLevels = map[string]bool{
+	"get":         true,
+	"start-next":  true,
+	"notify":      true,
+	"notify-next": true,
+}
+
+// JobIDFromTopic returns the job ID from a job execution topic of the form $aws/things/<thing>/jobs/<job ID>/...,
+// for example one received on a subscription to JobExecutionsFilter. If the device uses a BridgeTopicPrefix, map
+// the topic with Device.AWSTopic first.
+func JobIDFromTopic(topic string) (string, error) {
+	if _, err := ThingNameFromTopic(topic); err != nil {
+		return "", err
+	}
+
+	// The levels are "$aws", "things", the thing name, "jobs", the job ID, and the operation.
+	levels := strings.Split(topic, "/")
+	if len(levels) < 6 || levels[3] != "jobs" || levels[4] == "" || levels[5] == "" || jobsNonJobLevels[levels[4]] {
+		return "", fmt.Errorf("awsiotcore: topic %q is not of the form %s<name>/jobs/<job ID>/...", topic, reservedThingsPrefix)
+	}
+	return levels[4], nil
+}
+
+// JobStatusInProgress is the status of a job execution that the device is working on.
+const JobStatusInProgress = "IN_PROGRESS"
+
+// jobUpdate is the payload of a job execution update request.
+type jobUpdate struct {
+	Status        string            `json:"status"`
+	StatusDetails map[string]string `json:"statusDetails,omitempty"`
+}
+
+// ReportJobProgress updates the job execution with the given ID to IN_PROGRESS, with statusDetails consisting of
+// detail plus a "percent" key set to percent, which must be in [0, 100]. It's for long-running jobs such as OTA
+// updates to report how far along they are. It's published at QoS 1, or the QoS TopicQoS gives for the topic. It
+// waits for the publish to complete but not for AWS IoT's response, which is published to
+// JobTopic(jobID, "update/accepted") or JobTopic(jobID, "update/rejected").
+func (d *Device) ReportJobProgress(client mqtt.Client, jobID string, percent int, detail map[string]string) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("awsiotcore: job progress must be in [0, 100], got %d", percent)
+	}
+
+	details := make(map[string]string, len(detail)+1)
+	for k, v := range detail {
+		details[k] = v
+	}
+	details["percent"] = strconv.Itoa(percent)
+
+	topic := d.JobTopic(jobID, "update")
+	return PublishJSON(client, topic, d.topicQoS(topic, 1), false, jobUpdate{
+		Status:        JobStatusInProgress,
+		StatusDetails: details,
+	})
+}
@@ -0,0 +1,86 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReportJobProgress(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	if err := d.ReportJobProgress(client, "ota-1", 42, map[string]string{"step": "download"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if want := "$aws/things/foo/jobs/ota-1/update"; msgs[0].topic != want {
+		t.Errorf("got topic %q, want %q", msgs[0].topic, want)
+	}
+
+	var got jobUpdate
+	if err := json.Unmarshal(msgs[0].payload, &got); err != nil {
+		t.Fatalf("failed to parse payload: %v", err)
+	}
+	if got.Status != "IN_PROGRESS" || got.StatusDetails["percent"] != "42" || got.StatusDetails["step"] != "download" {
+		t.Errorf("got %+v, want IN_PROGRESS with percent 42 and step download", got)
+	}
+
+	if err := d.ReportJobProgress(client, "ota-1", 101, nil); err == nil {
+		t.Errorf("got nil error for percent 101, want non-nil")
+	}
+}
+
+func TestJobTopics(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	if got, want := d.JobExecutionsFilter(), "$aws/things/foo/jobs/+/#"; got != want {
+		t.Errorf("got filter %q, want %q", got, want)
+	}
+	if got, want := d.JobNotifyNextTopic(), "$aws/things/foo/jobs/notify-next"; got != want {
+		t.Errorf("got topic %q, want %q", got, want)
+	}
+}
+
+func TestJobIDFromTopic(t *testing.T) {
+	cases := []struct {
+		topic   string
+		want    string
+		wantErr bool
+	}{
+		{"$aws/things/foo/jobs/ota-1/update/accepted", "ota-1", false},
+		{"$aws/things/foo/jobs/ota-1/get", "ota-1", false},
+		{"$aws/things/foo/jobs/notify", "", true},
+		{"$aws/things/foo/jobs/get/accepted", "", true},
+		{"$aws/things/foo/jobs/ota-1", "", true},
+		{"$aws/things/foo/shadow/update/accepted", "", true},
+		{"things/foo/jobs/ota-1/get", "", true},
+	}
+
+	for _, c := range cases {
+		got, err := JobIDFromTopic(c.topic)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: got nil error, want non-nil", c.topic)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.topic, err)
+		} else if got != c.want {
+			t.Errorf("%q: got %q, want %q", c.topic, got, c.want)
+		}
+	}
+}
+
+func TestReportJobProgressTopicQoS(t *testing.T) {
+	d := &Device{DeviceID: "foo", TopicQoS: map[string]byte{"$aws/things/foo/jobs/+/update": 0}}
+	client := newFakeClient()
+	if err := d.ReportJobProgress(client, "job1", 50, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msgs := client.messages(); len(msgs) != 1 || msgs[0].qos != 0 {
+		t.Errorf("got messages %v, want one at the QoS from TopicQoS, 0", msgs)
+	}
+}
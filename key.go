@@ -0,0 +1,81 @@
+package awsiotcore
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadPrivateKey reads the private key in the file at path. The key may be PEM-encoded or raw DER, and is parsed as
+// PKCS #1 (RSA), PKCS #8, and SEC 1 (EC) in turn regardless of the PEM block's type, so keys whose label doesn't
+// match their encoding, e.g. a PKCS #8 key in a "RSA PRIVATE KEY" block, are loaded too. PEM blocks that aren't
+// keys, like the "EC PARAMETERS" block that openssl ecparam writes, are skipped. Encrypted keys are not supported.
+func LoadPrivateKey(path string) (crypto.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to read private key: %v", err)
+	}
+
+	key, err := parsePrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse private key in %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// parsePrivateKey parses the first private key in b, which may contain PEM blocks or a single DER-encoded key.
+func parsePrivateKey(b []byte) (crypto.PrivateKey, error) {
+	var ders [][]byte
+	for rest := b; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+			return nil, errors.New("encrypted private keys are not supported")
+		}
+		if block.Type != "CERTIFICATE" && block.Type != "EC PARAMETERS" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		ders = [][]byte{b}
+	}
+
+	for _, der := range ders {
+		if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParseECPrivateKey(der); err == nil {
+			return key, nil
+		}
+	}
+	return nil, errors.New("not a PKCS #1, PKCS #8, or SEC 1 private key in PEM or DER form")
+}
+
+// loadX509KeyPair is like tls.LoadX509KeyPair but reads the key with LoadPrivateKey.
+func loadX509KeyPair(certPath, keyPath string) (tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("awsiotcore: failed to read cert: %v", err)
+	}
+	key, err := LoadPrivateKey(keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// Re-encoding the key lets tls.X509KeyPair parse the cert chain and check that the key matches the cert.
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("awsiotcore: unsupported private key in %s: %w", keyPath, err)
+	}
+	return tls.X509KeyPair(certPEM, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
@@ -0,0 +1,85 @@
+package awsiotcore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecParams := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{0x06, 0x08}})
+
+	cases := []struct {
+		name     string
+		contents []byte
+		wantRSA  bool
+	}{
+		{"pkcs1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), true},
+		{"pkcs1 mislabeled", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), true},
+		{"pkcs8 mislabeled", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: pkcs8}), false},
+		{"sec1 after params", append(ecParams, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})...), false},
+		{"sec1 der", sec1, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key.pem")
+			writeFile(t, path, string(c.contents))
+
+			key, err := LoadPrivateKey(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := key.(*rsa.PrivateKey); ok != c.wantRSA {
+				t.Errorf("got key of type %T", key)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	writeFile(t, path, "not a key")
+	if _, err := LoadPrivateKey(path); err == nil || !strings.Contains(err.Error(), "PKCS #1, PKCS #8, or SEC 1") {
+		t.Errorf("got error %v, want one naming the formats tried", err)
+	}
+}
+
+func TestLoadX509KeyPair(t *testing.T) {
+	tc := writeTestCert(t, "foo", time.Now(), time.Now().Add(time.Hour))
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(tc.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, tc.keyPath, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: pkcs8})))
+
+	if _, err := loadX509KeyPair(tc.certPath, tc.keyPath); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	other := writeTestCert(t, "bar", time.Now(), time.Now().Add(time.Hour))
+	if _, err := loadX509KeyPair(tc.certPath, other.keyPath); err == nil {
+		t.Errorf("got nil error for mismatched key, want non-nil")
+	}
+}
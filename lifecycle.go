@@ -0,0 +1,42 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// LifecycleEvent is an event in the life of a device program, published by PublishLifecycle.
+type LifecycleEvent string
+
+const (
+	LifecycleStartup   LifecycleEvent = "startup"
+	LifecycleHeartbeat LifecycleEvent = "heartbeat"
+	LifecycleShutdown  LifecycleEvent = "shutdown"
+)
+
+// lifecycleMessage is the payload published by PublishLifecycle.
+type lifecycleMessage struct {
+	DeviceID  string         `json:"device_id"`
+	Event     LifecycleEvent `json:"event"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// LifecycleTopic returns the MQTT topic to which the device publishes lifecycle events.
+func (d *Device) LifecycleTopic() string {
+	return d.bridgeTopic(fmt.Sprintf("things/%v/lifecycle", d.DeviceID))
+}
+
+// PublishLifecycle publishes event to the device's lifecycle topic at QoS 1, or the QoS TopicQoS gives for the
+// topic. The payload is a JSON object of the form
+// {"device_id": "my-device", "event": "startup", "timestamp": "2023-04-22T18:30:00Z"}.
+func (d *Device) PublishLifecycle(client mqtt.Client, event LifecycleEvent) error {
+	msg := lifecycleMessage{
+		DeviceID:  d.ID(),
+		Event:     event,
+		Timestamp: d.clockOrSystem().Now().UTC(),
+	}
+	topic := d.LifecycleTopic()
+	return PublishJSON(client, topic, d.topicQoS(topic, 1), false, msg)
+}
@@ -0,0 +1,30 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPublishLifecycle(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	if err := d.PublishLifecycle(client, LifecycleStartup); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if msgs[0].topic != "things/foo/lifecycle" {
+		t.Errorf("got topic %q, want %q", msgs[0].topic, "things/foo/lifecycle")
+	}
+
+	var got lifecycleMessage
+	if err := json.Unmarshal(msgs[0].payload, &got); err != nil {
+		t.Fatalf("failed to parse payload: %v", err)
+	}
+	if got.DeviceID != "foo" || got.Event != LifecycleStartup || got.Timestamp.IsZero() {
+		t.Errorf("got %+v, want device ID foo, event startup, and a timestamp", got)
+	}
+}
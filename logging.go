@@ -0,0 +1,80 @@
+package awsiotcore
+
+import (
+	"bytes"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// messageLogger logs messages' topics, QoS, and sizes, and optionally their payloads.
+type messageLogger struct {
+	logger   mqtt.Logger
+	payloads bool
+}
+
+func (l messageLogger) log(direction, topic string, qos byte, retained bool, payload []byte) {
+	if l.payloads {
+		l.logger.Printf("awsiotcore: %s topic=%s qos=%d retained=%v bytes=%d payload=%q", direction, topic, qos, retained, len(payload), payload)
+		return
+	}
+	l.logger.Printf("awsiotcore: %s topic=%s qos=%d retained=%v bytes=%d", direction, topic, qos, retained, len(payload))
+}
+
+func (l messageLogger) handler(h mqtt.MessageHandler) mqtt.MessageHandler {
+	return func(c mqtt.Client, m mqtt.Message) {
+		l.log("received", m.Topic(), m.Qos(), m.Retained(), m.Payload())
+		if h != nil {
+			h(c, m)
+		}
+	}
+}
+
+// WithMessageLogging returns an option that logs each message delivered to the client's default publish handler,
+// i.e. each message that no subscription's handler matches. To log every message the client sends and receives,
+// wrap it with NewLoggingClient instead. Payloads are only logged if payloads is true, since they may be sensitive.
+func WithMessageLogging(logger mqtt.Logger, payloads bool) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetDefaultPublishHandler(messageLogger{logger: logger, payloads: payloads}.handler(opts.DefaultPublishHandler))
+		return nil
+	}
+}
+
+// LoggingClient wraps an mqtt.Client and logs the topic, QoS, and size of every message published with it and every
+// message delivered to the handlers of subscriptions made with it, which helps during development. It implements
+// mqtt.Client, so it can be passed to this package's publish helpers.
+type LoggingClient struct {
+	mqtt.Client
+
+	l messageLogger
+}
+
+// NewLoggingClient returns a LoggingClient that wraps client and logs to logger. Payloads are only logged if payloads
+// is true, since they may be sensitive.
+func NewLoggingClient(client mqtt.Client, logger mqtt.Logger, payloads bool) *LoggingClient {
+	return &LoggingClient{
+		Client: client,
+		l:      messageLogger{logger: logger, payloads: payloads},
+	}
+}
+
+func (c *LoggingClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	var b []byte
+	switch p := payload.(type) {
+	case []byte:
+		b = p
+	case string:
+		b = []byte(p)
+	case bytes.Buffer:
+		b = p.Bytes()
+	}
+	c.l.log("publish", topic, qos, retained, b)
+	return c.Client.Publish(topic, qos, retained, payload)
+}
+
+func (c *LoggingClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	return c.Client.Subscribe(topic, qos, c.l.handler(callback))
+}
+
+func (c *LoggingClient) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	return c.Client.SubscribeMultiple(filters, c.l.handler(callback))
+}
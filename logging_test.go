@@ -0,0 +1,61 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// bufLogger is an mqtt.Logger that records what's logged.
+type bufLogger struct {
+	lines []string
+}
+
+func (l *bufLogger) Println(v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(v...))
+}
+
+func (l *bufLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestLoggingClient(t *testing.T) {
+	fake := newFakeClient()
+	var logger bufLogger
+	client := NewLoggingClient(fake, &logger, false)
+
+	if err := PublishBytes(client, "things/foo/telemetry", 1, false, []byte("secret")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	received := false
+	client.Subscribe("things/foo/commands", 1, func(mqtt.Client, mqtt.Message) { received = true })
+	fake.deliver("things/foo/commands", []byte("reboot"))
+
+	if !received {
+		t.Errorf("message not delivered to the wrapped handler")
+	}
+	want := []string{
+		"awsiotcore: publish topic=things/foo/telemetry qos=1 retained=false bytes=6",
+		"awsiotcore: received topic=things/foo/commands qos=0 retained=false bytes=6",
+	}
+	if strings.Join(logger.lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got log lines %q, want %q", logger.lines, want)
+	}
+}
+
+func TestWithMessageLogging(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	var logger bufLogger
+	if err := WithMessageLogging(&logger, true)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts.DefaultPublishHandler(newFakeClient(), &fakeMessage{topic: "things/foo/other", payload: []byte("hi")})
+	want := `awsiotcore: received topic=things/foo/other qos=0 retained=false bytes=2 payload="hi"`
+	if len(logger.lines) != 1 || logger.lines[0] != want {
+		t.Errorf("got log lines %q, want [%q]", logger.lines, want)
+	}
+}
@@ -0,0 +1,87 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// DefaultRotateTimeout is the default time a ManagedClient waits to reconnect after rotating its certificate.
+const DefaultRotateTimeout = 30 * time.Second
+
+// ManagedClient is an mqtt.Client whose client certificate can be changed without creating a new client. Create one
+// with Device.NewManagedClient.
+type ManagedClient struct {
+	mqtt.Client
+
+	// Timeout is how long RotateCertificate waits to reconnect.
+	Timeout time.Duration
+
+	device *Device
+	cert   atomic.Pointer[tls.Certificate]
+	mu     sync.Mutex
+}
+
+// NewManagedClient is like NewClient but returns a ManagedClient, whose certificate may be rotated.
+func (d *Device) NewManagedClient(options ...func(*Device, *mqtt.ClientOptions) error) (*ManagedClient, error) {
+	opts, err := d.clientOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ManagedClient{
+		Timeout: DefaultRotateTimeout,
+		device:  d,
+	}
+
+	// Serve the cert through a callback so that each connection attempt uses whichever cert is current.
+	if len(opts.TLSConfig.Certificates) > 0 {
+		m.cert.Store(&opts.TLSConfig.Certificates[0])
+		opts.TLSConfig.Certificates = nil
+	}
+	opts.TLSConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		if cert := m.cert.Load(); cert != nil {
+			return cert, nil
+		}
+		return &tls.Certificate{}, nil
+	}
+
+	m.Client = mqtt.NewClient(opts)
+	return m, nil
+}
+
+// RotateCertificate loads the cert and key at the given paths, disconnects, and reconnects using them. If the new
+// key pair can't be loaded then nothing changes. If the client can't reconnect with the new cert within Timeout it
+// reconnects with the old one and returns an error, so the old cert must remain valid until RotateCertificate
+// returns successfully. On success the Device's CertPath and PrivKeyPath are updated to the new paths.
+func (m *ManagedClient) RotateCertificate(newCertPath, newKeyPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cert, err := loadX509KeyPair(newCertPath, newKeyPath)
+	if err != nil {
+		return fmt.Errorf("awsiotcore: failed to load x509 key pair: %w", err)
+	}
+
+	old := m.cert.Swap(&cert)
+	if err := m.reconnect(); err != nil {
+		m.cert.Store(old)
+		if rerr := m.reconnect(); rerr != nil {
+			return fmt.Errorf("awsiotcore: failed to connect with new cert (%v) and with old cert: %w", err, rerr)
+		}
+		return fmt.Errorf("awsiotcore: failed to connect with new cert, reverted to old cert: %w", err)
+	}
+
+	m.device.CertPath = newCertPath
+	m.device.PrivKeyPath = newKeyPath
+	return nil
+}
+
+func (m *ManagedClient) reconnect() error {
+	m.Client.Disconnect(disconnectQuiesce)
+	return waitToken(m.Client.Connect(), m.Timeout)
+}
@@ -0,0 +1,72 @@
+package awsiotcore
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRotateCertificate(t *testing.T) {
+	d := testDevice(t)
+	m, err := d.NewManagedClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Swap in a fake so that reconnecting doesn't need a broker.
+	fake := newFakeClient()
+	m.Client = fake
+
+	newCert := writeTestCert(t, "foo-new", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	oldCert := m.cert.Load()
+	if err := m.RotateCertificate(newCert.certPath, newCert.keyPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.cert.Load() == oldCert {
+		t.Errorf("cert was not rotated")
+	}
+	if d.CertPath != newCert.certPath || d.PrivKeyPath != newCert.keyPath {
+		t.Errorf("device paths not updated: got %q and %q", d.CertPath, d.PrivKeyPath)
+	}
+}
+
+func TestRotateCertificateRevert(t *testing.T) {
+	d := testDevice(t)
+	m, err := d.NewManagedClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The broker only accepts the old cert.
+	oldCert := m.cert.Load()
+	oldCertPath := d.CertPath
+	fake := newFakeClient()
+	fake.connect = func() error {
+		if m.cert.Load() != oldCert {
+			return errors.New("rejected")
+		}
+		return nil
+	}
+	m.Client = fake
+
+	newCert := writeTestCert(t, "foo-new", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err := m.RotateCertificate(newCert.certPath, newCert.keyPath); err == nil {
+		t.Errorf("got nil error, want non-nil")
+	}
+	if m.cert.Load() != oldCert {
+		t.Errorf("cert was not reverted")
+	}
+	if !fake.IsConnected() {
+		t.Errorf("not reconnected with old cert")
+	}
+	if d.CertPath != oldCertPath {
+		t.Errorf("got CertPath %q, want %q", d.CertPath, oldCertPath)
+	}
+
+	if err := m.RotateCertificate(newCert.certPath, "missing.pem"); err == nil {
+		t.Errorf("got nil error for missing key, want non-nil")
+	}
+	if m.cert.Load() != oldCert {
+		t.Errorf("cert changed despite failure to load new key")
+	}
+}
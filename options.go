@@ -0,0 +1,342 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// This file contains options that may be passed to NewClient. See NewClient for more info.
+
+// addOnConnectHandler adds h to the ClientOptions' OnConnect handler, preserving any handler that was set by
+// an earlier option. paho only holds one handler, so options that need to run on connect must chain rather than set.
+func addOnConnectHandler(opts *mqtt.ClientOptions, h mqtt.OnConnectHandler) {
+	prev := opts.OnConnect
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		if prev != nil {
+			prev(c)
+		}
+		h(c)
+	})
+}
+
+// addConnectionLostHandler is like addOnConnectHandler but for the ConnectionLost handler.
+func addConnectionLostHandler(opts *mqtt.ClientOptions, h mqtt.ConnectionLostHandler) {
+	prev := opts.OnConnectionLost
+	opts.SetConnectionLostHandler(func(c mqtt.Client, err error) {
+		if prev != nil {
+			prev(c, err)
+		}
+		h(c, err)
+	})
+}
+
+// addReconnectingHandler is like addOnConnectHandler but for the Reconnecting handler.
+func addReconnectingHandler(opts *mqtt.ClientOptions, h mqtt.ReconnectHandler) {
+	prev := opts.OnReconnecting
+	opts.SetReconnectingHandler(func(c mqtt.Client, o *mqtt.ClientOptions) {
+		if prev != nil {
+			prev(c, o)
+		}
+		h(c, o)
+	})
+}
+
+// WithConnectRetry returns an option that makes the client retry the initial connection at the given interval
+// rather than fail. This is handy for devices that may boot before the network is up. Note that with connect retry
+// enabled, the token returned by Connect does not complete until the connection succeeds or Disconnect is called.
+func WithConnectRetry(interval time.Duration) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetConnectRetry(true)
+		opts.SetConnectRetryInterval(interval)
+		return nil
+	}
+}
+
+// WithInitialSubscriptions returns an option that subscribes to the given topic filters, with the given QoS, each time
+// the client connects. Because subscriptions are made again on every connect they survive reconnects, even with a
+// clean session. Subscription failures are logged to paho's ERROR logger.
+func WithInitialSubscriptions(subs map[string]byte, handler mqtt.MessageHandler) func(*Device, *mqtt.ClientOptions) error {
+	filters := make(map[string]byte, len(subs))
+	for topic, qos := range subs {
+		filters[topic] = qos
+	}
+
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		for topic, qos := range filters {
+			if err := checkQoS(qos); err != nil {
+				return fmt.Errorf("awsiotcore: initial subscription to %s: %w", topic, err)
+			}
+		}
+
+		addOnConnectHandler(opts, func(c mqtt.Client) {
+			if len(filters) == 0 {
+				return
+			}
+
+			if err := waitSubscribe(c.SubscribeMultiple(filters, handler)); err != nil {
+				mqtt.ERROR.Printf("awsiotcore: failed to make initial subscriptions: %v", err)
+			}
+		})
+		return nil
+	}
+}
+
+// WithUnorderedDelivery returns an option that lets the client call message handlers concurrently, each in its own
+// goroutine, rather than one at a time in the order messages arrive. This can greatly increase throughput when
+// handlers do independent work, but handlers must then be safe for concurrent use and must not assume any ordering.
+func WithUnorderedDelivery() func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetOrderMatters(false)
+		return nil
+	}
+}
+
+// WithClientIDSuffix returns an option that appends suffix to the MQTT client ID, which is otherwise the device ID.
+// AWS IoT disconnects a client when another connects with the same client ID, so this allows several processes on
+// one device to connect at once, e.g. with suffixes "-telemetry" and "-commands". Topics are still derived from the
+// device ID.
+func WithClientIDSuffix(suffix string) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetClientID(opts.ClientID + suffix)
+		return nil
+	}
+}
+
+// maxClientIDLen is the maximum length in bytes of an AWS IoT MQTT client ID.
+// See https://docs.aws.amazon.com/general/latest/gr/iot-core.html.
+const maxClientIDLen = 128
+
+// WithClientIDPrefix returns an option that prepends prefix to the MQTT client ID, e.g. to namespace client IDs by
+// fleet as "fleetA:<device ID>" so that they're easy to filter in logs. Topics are still derived from the device ID.
+// It returns an error if the resulting client ID is longer than AWS IoT allows.
+func WithClientIDPrefix(prefix string) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		id := prefix + opts.ClientID
+		if len(id) > maxClientIDLen {
+			return fmt.Errorf("awsiotcore: client ID %q is %d bytes long; the maximum is %d", id, len(id), maxClientIDLen)
+		}
+
+		opts.SetClientID(id)
+		return nil
+	}
+}
+
+// WithKeepAliveJitter returns an option that sets the keepalive to a random duration in [base, base+jitter). When
+// a fleet of devices reconnects at once after an outage, identical keepalives keep their pings synchronized; jitter
+// spreads them out. Note that paho's keepalive has a resolution of one second, and that AWS IoT accepts keepalives
+// of 30 to 1200 seconds.
+func WithKeepAliveJitter(base, jitter time.Duration) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		keepAlive := base
+		if jitter > 0 {
+			keepAlive += time.Duration(rand.Int63n(int64(jitter)))
+		}
+		opts.SetKeepAlive(keepAlive)
+		return nil
+	}
+}
+
+// WithConnectAttemptHandler returns an option that calls h before each connection attempt, including the attempts
+// paho makes internally when retrying and reconnecting. h receives the broker being connected to and the TLS config
+// that will be used, and returns the TLS config to use for the attempt. This can be used to log and time attempts,
+// or to swap in refreshed credentials. If an earlier option set a handler, h receives the config that handler
+// returned. It returns an error if combined with WithPinnedDNS or WithoutSNI; see there.
+func WithConnectAttemptHandler(h func(broker *url.URL, tlsCfg *tls.Config) *tls.Config) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if opts.CustomOpenConnectionFn != nil {
+			return fmt.Errorf("awsiotcore: connect attempt handlers can't be combined with WithPinnedDNS or WithoutSNI")
+		}
+
+		prev := opts.OnConnectAttempt
+		opts.SetConnectionAttemptHandler(func(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
+			if prev != nil {
+				tlsCfg = prev(broker, tlsCfg)
+			}
+			return h(broker, tlsCfg)
+		})
+		return nil
+	}
+}
+
+// WithProtocolVersion returns an option that sets the MQTT protocol version explicitly: 3 for MQTT 3.1 or 4 for
+// MQTT 3.1.1. By default paho tries 3.1.1 and falls back to 3.1 if the broker refuses it. AWS IoT supports 3.1.1.
+func WithProtocolVersion(v uint) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if v != 3 && v != 4 {
+			return fmt.Errorf("awsiotcore: unsupported MQTT protocol version %d; must be 3 (MQTT 3.1) or 4 (MQTT 3.1.1)", v)
+		}
+
+		opts.SetProtocolVersion(v)
+		return nil
+	}
+}
+
+// WithTLSConfig returns an option that calls mutate with the tls.Config that NewClient built, so that any of its
+// fields may be customized, e.g. cipher suites or verification callbacks. Since options are applied in order,
+// mutate sees the effects of earlier options.
+func WithTLSConfig(mutate func(*tls.Config) error) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if opts.TLSConfig == nil {
+			opts.SetTLSConfig(&tls.Config{})
+		}
+		return mutate(opts.TLSConfig)
+	}
+}
+
+// WithResumeSubs returns an option that sets whether the client keeps subscribe and unsubscribe requests that
+// haven't been acknowledged when the connection goes down, and resends them once it's back. paho resends them on
+// every automatic reconnect, whatever the clean session setting, and on Connect only if clean session is disabled,
+// since with it enabled Connect clears the store. Without it, the tokens of such requests complete with an error
+// when the connection is lost.
+//
+// It also lets Subscribe and Unsubscribe be called while the client isn't connected: the request is stored and
+// sent once the client connects, except while reconnecting with clean session enabled, when the call still fails.
+// Without it, such calls fail with "not currently connected and ResumeSubs not set".
+func WithResumeSubs(resume bool) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetResumeSubs(resume)
+		return nil
+	}
+}
+
+// WithManualAck returns an option that stops the client from acknowledging QoS 1 messages automatically when their
+// handlers return. Handlers must instead call the message's Ack method, e.g. once the message has been durably
+// processed, which gives at-least-once processing: a message that is never acked is redelivered by the broker if
+// the session persists. Messages that don't need it, such as QoS 0 messages, ignore Ack.
+func WithManualAck() func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetAutoAckDisabled(true)
+		return nil
+	}
+}
+
+// WithWebSocketHeaders returns an option that adds the given headers to the HTTP request that opens a WebSocket
+// connection, e.g. for authenticating to a proxy. They're only sent when connecting to a ws:// or wss:// broker, so
+// this option has no effect unless another option adds one; NewClient connects over TLS on port 8883 by default.
+// The headers are copied, so changing h afterwards has no effect.
+func WithWebSocketHeaders(h http.Header) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetHTTPHeaders(h.Clone())
+		return nil
+	}
+}
+
+// OfflineTopic returns the MQTT topic to which WithShadowOfflineWill sends the device's will.
+func (d *Device) OfflineTopic() string {
+	return d.bridgeTopic(fmt.Sprintf("things/%v/offline", d.DeviceID))
+}
+
+// WithShadowOfflineWill returns an option that sets the client's will to a classic shadow update that reports the
+// device as disconnected:
+//
+//	{"state":{"reported":{"connected":false}}}
+//
+// The broker publishes it if the client disconnects without sending a DISCONNECT. The Device Shadow service ignores
+// wills sent to reserved topics, so the will goes to OfflineTopic instead and an AWS IoT rule must republish it to
+// the shadow update topic for the shadow to be updated, e.g. a rule with the query
+//
+//	SELECT * FROM 'things/+/offline'
+//
+// and a republish action to $$aws/things/${topic(2)}/shadow/update. Together with reporting connected as true on
+// connect, that keeps the shadow's view of the device's presence up to date. The will is sent at QoS 1 and isn't
+// retained.
+func WithShadowOfflineWill() func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetBinaryWill(d.OfflineTopic(), []byte(`{"state":{"reported":{"connected":false}}}`), 1, false)
+		return nil
+	}
+}
+
+// WithEndpointOverrideForTesting returns an option that connects to the broker at host and port instead of the
+// device's endpoint, while still sending the endpoint as the TLS Server Name Indication and verifying the server's
+// cert against it. It's meant for integration tests against a local broker, e.g. on 127.0.0.1, that presents a test
+// cert issued for the real endpoint by a CA in CACerts.
+func WithEndpointOverrideForTesting(host string, port int) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		broker := MQTTBroker{Scheme: "ssl", Host: host, Port: port}
+		opts.Servers = nil
+		opts.AddBroker(broker.URL())
+		return nil
+	}
+}
+
+// WithServerCertVerifier returns an option that calls verify during the TLS handshake with the server's raw certs
+// and the chains crypto/tls verified them to, as tls.Config.VerifyPeerCertificate does. If verify returns an error
+// the handshake fails. It runs after, not instead of, normal verification against CACerts; to rely on verify alone,
+// e.g. for a private server CA with custom validation rules, also set InsecureSkipVerify with WithTLSConfig, in
+// which case verifiedChains is nil. If an earlier option set a verifier then it's called first.
+func WithServerCertVerifier(verify func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if opts.TLSConfig == nil {
+			opts.SetTLSConfig(&tls.Config{})
+		}
+
+		prev := opts.TLSConfig.VerifyPeerCertificate
+		opts.TLSConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if prev != nil {
+				if err := prev(rawCerts, verifiedChains); err != nil {
+					return err
+				}
+			}
+			return verify(rawCerts, verifiedChains)
+		}
+		return nil
+	}
+}
+
+// WithCleanSession returns an option that sets whether the client asks for a clean session when it connects. With
+// clean set to false AWS IoT keeps the client's subscriptions, and queues QoS 1 messages for them, while it's
+// disconnected, but only for a limited time (an hour by default) and up to a limited number of messages, after
+// which they're lost. Since that's easy to mistake for unlimited durability, the option logs a warning when clean
+// is false. See https://docs.aws.amazon.com/iot/latest/developerguide/mqtt.html#mqtt-persistent-sessions.
+func WithCleanSession(clean bool) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if !clean {
+			mqtt.WARN.Printf("awsiotcore: clean session disabled for %s; AWS IoT expires persistent sessions, by default an hour after the client disconnects, and limits the messages queued for them", opts.ClientID)
+		}
+
+		opts.SetCleanSession(clean)
+		return nil
+	}
+}
+
+// WithMaxReconnectInterval returns an option that sets the longest the client waits between automatic reconnect
+// attempts. After losing its connection the client tries to reconnect straight away, then waits 1 second before the
+// next attempt, doubling the wait after each failure up to max. paho doesn't allow that first 1 second wait to be
+// changed; max applies from the second wait on.
+func WithMaxReconnectInterval(max time.Duration) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetMaxReconnectInterval(max)
+		return nil
+	}
+}
+
+// WithUsername returns an option that sets the MQTT username, e.g. for a custom authorizer that reads metadata such
+// as the firmware version from it, or for a bridge that expects credentials. If the device has a CustomAuthorizer
+// then the query string that names it is kept after u.
+func WithUsername(u string) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		user := u
+		if d.CustomAuthorizer != "" {
+			user = d.customAuthorizerUsername(u)
+		}
+		opts.SetUsername(user)
+		return nil
+	}
+}
+
+// WithPassword returns an option that sets the MQTT password. With a CustomAuthorizer it's how the authorizer's
+// token is sent.
+func WithPassword(p string) func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		opts.SetPassword(p)
+		return nil
+	}
+}
@@ -0,0 +1,328 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// testOpts returns ClientOptions like those NewClient builds, without needing certs on disk.
+func testOpts(d *Device) *mqtt.ClientOptions {
+	broker := d.Broker()
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(broker.URL())
+	opts.SetClientID(d.DeviceID)
+	opts.SetTLSConfig(&tls.Config{ServerName: d.Endpoint})
+	return opts
+}
+
+func TestWithConnectRetry(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithConnectRetry(5*time.Second)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !opts.ConnectRetry {
+		t.Errorf("ConnectRetry not enabled")
+	}
+	if opts.ConnectRetryInterval != 5*time.Second {
+		t.Errorf("got ConnectRetryInterval %v, want %v", opts.ConnectRetryInterval, 5*time.Second)
+	}
+}
+
+func TestWithInitialSubscriptions(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	subs := map[string]byte{
+		"things/foo/commands":                 1,
+		"$aws/things/foo/shadow/update/delta": 0,
+	}
+	if err := WithInitialSubscriptions(subs, func(mqtt.Client, mqtt.Message) {})(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client := newFakeClient()
+	opts.OnConnect(client)
+	for topic := range subs {
+		if _, ok := client.subs[topic]; !ok {
+			t.Errorf("not subscribed to %q", topic)
+		}
+	}
+}
+
+func TestWithUnorderedDelivery(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithUnorderedDelivery()(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts.Order {
+		t.Errorf("Order is still set")
+	}
+}
+
+func TestWithClientIDSuffix(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithClientIDSuffix("-commands")(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "foo-commands"; opts.ClientID != want {
+		t.Errorf("got client ID %q, want %q", opts.ClientID, want)
+	}
+	if want := "things/foo/telemetry"; d.TelemetryTopic() != want {
+		t.Errorf("got telemetry topic %q, want %q", d.TelemetryTopic(), want)
+	}
+}
+
+func TestWithClientIDPrefix(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithClientIDPrefix("fleetA:")(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "fleetA:foo"; opts.ClientID != want {
+		t.Errorf("got client ID %q, want %q", opts.ClientID, want)
+	}
+	if want := "things/foo/telemetry"; d.TelemetryTopic() != want {
+		t.Errorf("got telemetry topic %q, want %q", d.TelemetryTopic(), want)
+	}
+
+	if err := WithClientIDPrefix(strings.Repeat("a", 126))(d, opts); err == nil {
+		t.Errorf("got nil error for client ID over 128 bytes, want non-nil")
+	}
+}
+
+func TestWithKeepAliveJitter(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	for i := 0; i < 100; i++ {
+		opts := testOpts(d)
+		if err := WithKeepAliveJitter(60*time.Second, 30*time.Second)(d, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if opts.KeepAlive < 60 || opts.KeepAlive >= 90 {
+			t.Errorf("got keepalive %d seconds, want in [60, 90)", opts.KeepAlive)
+		}
+	}
+}
+
+func TestWithConnectAttemptHandler(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+
+	var attempts []string
+	replacement := &tls.Config{ServerName: "replaced"}
+	first := WithConnectAttemptHandler(func(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
+		attempts = append(attempts, "first:"+tlsCfg.ServerName)
+		return replacement
+	})
+	second := WithConnectAttemptHandler(func(broker *url.URL, tlsCfg *tls.Config) *tls.Config {
+		attempts = append(attempts, "second:"+tlsCfg.ServerName)
+		return tlsCfg
+	})
+	for _, option := range []func(*Device, *mqtt.ClientOptions) error{first, second} {
+		if err := option(d, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	got := opts.OnConnectAttempt(opts.Servers[0], opts.TLSConfig)
+	if got != replacement {
+		t.Errorf("got TLS config %v, want the one returned by the first handler", got)
+	}
+	if len(attempts) != 2 || attempts[0] != "first:myendpoint" || attempts[1] != "second:replaced" {
+		t.Errorf("got handler calls %v, want [first:myendpoint second:replaced]", attempts)
+	}
+}
+
+func TestWithProtocolVersion(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithProtocolVersion(4)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.ProtocolVersion != 4 {
+		t.Errorf("got protocol version %d, want 4", opts.ProtocolVersion)
+	}
+
+	if err := WithProtocolVersion(5)(d, opts); err == nil {
+		t.Errorf("got nil error for version 5, want non-nil")
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	err := WithTLSConfig(func(c *tls.Config) error {
+		c.MinVersion = tls.VersionTLS13
+		return nil
+	})(d, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts.TLSConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("got MinVersion %x, want %x", opts.TLSConfig.MinVersion, tls.VersionTLS13)
+	}
+	if opts.TLSConfig.ServerName != "myendpoint" {
+		t.Errorf("got ServerName %q, want %q", opts.TLSConfig.ServerName, "myendpoint")
+	}
+}
+
+func TestWithResumeSubs(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithResumeSubs(true)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !opts.ResumeSubs {
+		t.Errorf("ResumeSubs not set")
+	}
+}
+
+func TestWithManualAck(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithManualAck()(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !opts.AutoAckDisabled {
+		t.Errorf("AutoAckDisabled not set")
+	}
+}
+
+func TestWithWebSocketHeaders(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	h := http.Header{"User-Agent": []string{"sensor/1.0"}}
+	if err := WithWebSocketHeaders(h)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h.Set("User-Agent", "changed")
+
+	if got := opts.HTTPHeaders.Get("User-Agent"); got != "sensor/1.0" {
+		t.Errorf("got User-Agent %q, want %q", got, "sensor/1.0")
+	}
+}
+
+func TestWithShadowOfflineWill(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithShadowOfflineWill()(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !opts.WillEnabled || opts.WillTopic != "things/foo/offline" || opts.WillQos != 1 || opts.WillRetained {
+		t.Errorf("got will enabled %v, topic %q, QoS %d, retained %v; want an unretained QoS 1 will on the offline topic",
+			opts.WillEnabled, opts.WillTopic, opts.WillQos, opts.WillRetained)
+	}
+	if got, want := string(opts.WillPayload), `{"state":{"reported":{"connected":false}}}`; got != want {
+		t.Errorf("got will payload %s, want %s", got, want)
+	}
+}
+
+func TestWithEndpointOverrideForTesting(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithEndpointOverrideForTesting("127.0.0.1", 18883)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(opts.Servers) != 1 || opts.Servers[0].String() != "ssl://127.0.0.1:18883" {
+		t.Errorf("got servers %v, want [ssl://127.0.0.1:18883]", opts.Servers)
+	}
+	if got := opts.TLSConfig.ServerName; got != "myendpoint" {
+		t.Errorf("got ServerName %q, want %q", got, "myendpoint")
+	}
+}
+
+func TestWithServerCertVerifier(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+
+	var calls []string
+	errRejected := errors.New("rejected")
+	first := WithServerCertVerifier(func([][]byte, [][]*x509.Certificate) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	second := WithServerCertVerifier(func([][]byte, [][]*x509.Certificate) error {
+		calls = append(calls, "second")
+		return errRejected
+	})
+	for _, option := range []func(*Device, *mqtt.ClientOptions) error{first, second} {
+		if err := option(d, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := opts.TLSConfig.VerifyPeerCertificate(nil, nil); !errors.Is(err, errRejected) {
+		t.Errorf("got error %v, want %v", err, errRejected)
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("got verifier calls %v, want [first second]", calls)
+	}
+}
+
+func TestWithCleanSession(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithCleanSession(false)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts.CleanSession {
+		t.Errorf("CleanSession still set")
+	}
+}
+
+func TestWithMaxReconnectInterval(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithMaxReconnectInterval(30*time.Second)(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if opts.MaxReconnectInterval != 30*time.Second {
+		t.Errorf("got MaxReconnectInterval %v, want %v", opts.MaxReconnectInterval, 30*time.Second)
+	}
+}
+
+func TestWithUsernameAndPassword(t *testing.T) {
+	d := &Device{Endpoint: "myendpoint", DeviceID: "foo"}
+	opts := testOpts(d)
+	for _, option := range []func(*Device, *mqtt.ClientOptions) error{WithUsername("fw=1.2"), WithPassword("token")} {
+		if err := option(d, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if opts.Username != "fw=1.2" || opts.Password != "token" {
+		t.Errorf("got username %q and password %q, want fw=1.2 and token", opts.Username, opts.Password)
+	}
+
+	// Applying the same option again, e.g. when an options slice is reused, must not repeat the authorizer query.
+	d.CustomAuthorizer = "my-authorizer"
+	withUsername := WithUsername("fw=1.2")
+	for i := 0; i < 2; i++ {
+		if err := withUsername(d, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "fw=1.2?x-amz-customauthorizer-name=my-authorizer"; opts.Username != want {
+			t.Errorf("application %d: got username %q, want %q", i+1, opts.Username, want)
+		}
+	}
+}
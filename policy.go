@@ -0,0 +1,168 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Policy variables commonly used in the topic resources of AWS IoT policies. See
+// https://docs.aws.amazon.com/iot/latest/developerguide/iot-policy-variables.html.
+const (
+	PolicyVarClientID  = "${iot:ClientId}"
+	PolicyVarThingName = "${iot:Connection.Thing.ThingName}"
+)
+
+// PolicyVariables holds the values that AWS IoT substitutes for policy variables when it evaluates a connection's
+// policy.
+type PolicyVariables struct {
+	ClientID  string
+	ThingName string
+}
+
+// PolicyVariables returns the policy variables for a connection made by a client created with NewClient, i.e. with
+// the device ID as both the client ID and the thing name. If the client ID is changed, e.g. with WithClientIDSuffix,
+// set ClientID accordingly.
+func (d *Device) PolicyVariables() PolicyVariables {
+	return PolicyVariables{
+		ClientID:  d.DeviceID,
+		ThingName: d.DeviceID,
+	}
+}
+
+// Expand replaces the policy variables in s with their values. It can be used to build a topic from the same
+// template that a policy uses, e.g. "things/${iot:ClientId}/telemetry".
+func (v PolicyVariables) Expand(s string) string {
+	return strings.NewReplacer(
+		PolicyVarClientID, v.ClientID,
+		PolicyVarThingName, v.ThingName,
+	).Replace(s)
+}
+
+// Allows reports whether the policy resource would match topic once policy variables are expanded. resource may be
+// a full ARN such as "arn:aws:iot:us-west-2:123456789012:topic/things/${iot:ClientId}/*" or just the part after
+// "topic/" or "topicfilter/". As in policies, "*" matches any sequence of characters, including "/", and "?" matches
+// any single character.
+//
+// This catches mismatches between the topics a device uses and those its policy allows before the device is
+// deployed, rather than when AWS IoT disconnects it. It does not evaluate whole policies, so it knows nothing of
+// actions, Deny statements, or conditions.
+func (v PolicyVariables) Allows(resource, topic string) bool {
+	if strings.HasPrefix(resource, "arn:") {
+		for _, prefix := range []string{":topicfilter/", ":topic/"} {
+			if i := strings.Index(resource, prefix); i >= 0 {
+				resource = resource[i+len(prefix):]
+				break
+			}
+		}
+	}
+
+	return globMatch(v.Expand(resource), topic)
+}
+
+// globMatch reports whether s matches pattern, in which "*" matches any sequence of characters and "?" matches any
+// single character.
+func globMatch(pattern, s string) bool {
+	// Backtracking is only needed to the most recent "*", so this runs in O(len(pattern) * len(s)) in the worst case.
+	p, i := 0, 0
+	star, match := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, match = p, i
+			p++
+		case star >= 0:
+			p = star + 1
+			match++
+			i = match
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// publishTopicNames are the keys of the topics in Topics to which the device publishes. It subscribes to the rest.
+var publishTopicNames = map[string]bool{
+	"telemetry":     true,
+	"config":        true,
+	"command_ack":   true,
+	"lifecycle":     true,
+	"heartbeat":     true,
+	"offline":       true,
+	"shadow_get":    true,
+	"shadow_update": true,
+}
+
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// GeneratePolicy returns an AWS IoT policy document that allows the device to connect with its ID as the client ID,
+// publish to the topics in Topics that it publishes to and to its job execution update topics, and subscribe to and
+// receive messages on the rest. Topics are given without BridgeTopicPrefix, since the policy is evaluated by AWS IoT.
+//
+// The ARNs use the region from the endpoint if it's of the form <prefix>.iot.<region>.amazonaws.com and "*"
+// otherwise, and "*" for the account, which the Device doesn't know. If the client ID is changed, e.g. with
+// WithClientIDSuffix, the iot:Connect resource must be changed accordingly.
+func (d *Device) GeneratePolicy() (json.RawMessage, error) {
+	if err := ValidateDeviceID(d.ID()); err != nil {
+		return nil, err
+	}
+
+	arn := "arn:aws:iot:" + d.endpointRegion() + ":*:"
+	var publish, subscribe, receive []string
+	for name, topic := range d.Topics() {
+		topic = d.AWSTopic(topic)
+		if publishTopicNames[name] {
+			publish = append(publish, arn+"topic/"+topic)
+			continue
+		}
+
+		// Subscribe resources match the filter as written, with MQTT wildcards taken literally, whereas Receive
+		// resources match the topics of the messages the filter delivers.
+		subscribe = append(subscribe, arn+"topicfilter/"+topic)
+		receive = append(receive, arn+"topic/"+strings.NewReplacer("+", "*", "#", "*").Replace(topic))
+	}
+	publish = append(publish, arn+"topic/"+d.AWSTopic(d.JobTopic("*", "update")))
+	sort.Strings(publish)
+	sort.Strings(subscribe)
+	sort.Strings(receive)
+
+	return json.Marshal(policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{
+			{Effect: "Allow", Action: []string{"iot:Connect"}, Resource: []string{arn + "client/" + d.ID()}},
+			{Effect: "Allow", Action: []string{"iot:Publish"}, Resource: publish},
+			{Effect: "Allow", Action: []string{"iot:Subscribe"}, Resource: subscribe},
+			{Effect: "Allow", Action: []string{"iot:Receive"}, Resource: receive},
+		},
+	})
+}
+
+// endpointRegion returns the region of an endpoint of the form <prefix>.iot.<region>.amazonaws.com, or "*" if the
+// endpoint isn't of that form.
+func (d *Device) endpointRegion() string {
+	rest, ok := strings.CutSuffix(d.Endpoint, ".amazonaws.com")
+	if !ok {
+		return "*"
+	}
+	if _, region, ok := strings.Cut(rest, ".iot."); ok && region != "" && !strings.Contains(region, ".") {
+		return region
+	}
+	return "*"
+}
@@ -0,0 +1,108 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPolicyVariablesExpand(t *testing.T) {
+	v := PolicyVariables{ClientID: "foo-telemetry", ThingName: "foo"}
+	got := v.Expand("things/${iot:Connection.Thing.ThingName}/clients/${iot:ClientId}")
+	if want := "things/foo/clients/foo-telemetry"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPolicyVariablesAllows(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	v := d.PolicyVariables()
+
+	cases := []struct {
+		resource string
+		topic    string
+		want     bool
+	}{
+		{"arn:aws:iot:us-west-2:123456789012:topic/things/${iot:ClientId}/telemetry", "things/foo/telemetry", true},
+		{"arn:aws:iot:us-west-2:123456789012:topic/things/${iot:ClientId}/telemetry", "things/bar/telemetry", false},
+		{"arn:aws:iot:us-west-2:123456789012:topicfilter/things/${iot:ClientId}/*", "things/foo/commands/reboot", true},
+		{"things/${iot:Connection.Thing.ThingName}/*", "things/foo/telemetry", true},
+		{"things/${iot:Connection.Thing.ThingName}/*", "things/foobar/telemetry", false},
+		{"things/fo?/telemetry", "things/foo/telemetry", true},
+		{"*", "anything/at/all", true},
+		{"things/*/telemetry", "things/foo/telemetry/extra", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.resource+" "+c.topic, func(t *testing.T) {
+			if got := v.Allows(c.resource, c.topic); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestGeneratePolicy(t *testing.T) {
+	d := &Device{Endpoint: "abc123-ats.iot.us-west-2.amazonaws.com", DeviceID: "foo", BridgeTopicPrefix: "aws/"}
+	b, err := d.GeneratePolicy()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc policyDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("failed to unmarshal policy: %v", err)
+	}
+	resources := make(map[string][]string)
+	for _, s := range doc.Statement {
+		if s.Effect != "Allow" || len(s.Action) != 1 {
+			t.Fatalf("unexpected statement %+v", s)
+		}
+		resources[s.Action[0]] = s.Resource
+	}
+
+	const arn = "arn:aws:iot:us-west-2:*:"
+	want := map[string]string{
+		"iot:Connect":   arn + "client/foo",
+		"iot:Publish":   arn + "topic/$aws/things/foo/jobs/*/update",
+		"iot:Subscribe": arn + "topicfilter/$aws/things/foo/jobs/+/#",
+		"iot:Receive":   arn + "topic/$aws/things/foo/jobs/*/*",
+	}
+	for action, resource := range want {
+		if !containsString(resources[action], resource) {
+			t.Errorf("%s resources %q do not contain %q", action, resources[action], resource)
+		}
+	}
+	if containsString(resources["iot:Publish"], arn+"topic/things/foo/commands") {
+		t.Errorf("iot:Publish resources contain the command topic")
+	}
+	if !containsString(resources["iot:Receive"], arn+"topic/things/foo/commands") {
+		t.Errorf("iot:Receive resources do not contain the command topic")
+	}
+
+	if _, err := (&Device{}).GeneratePolicy(); err == nil {
+		t.Errorf("got nil error for device with no ID, want non-nil")
+	}
+}
+
+func TestEndpointRegion(t *testing.T) {
+	cases := map[string]string{
+		"abc123-ats.iot.us-west-2.amazonaws.com": "us-west-2",
+		"abc123.iot.eu-central-1.amazonaws.com":  "eu-central-1",
+		"iot.example.com":                        "*",
+		"localhost":                              "*",
+	}
+	for endpoint, want := range cases {
+		if got := (&Device{Endpoint: endpoint}).endpointRegion(); got != want {
+			t.Errorf("%s: got %q, want %q", endpoint, got, want)
+		}
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
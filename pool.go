@@ -0,0 +1,37 @@
+package awsiotcore
+
+import (
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+type pooledMessage struct {
+	client mqtt.Client
+	msg    mqtt.Message
+}
+
+// PooledHandler returns a handler that passes messages to inner on a fixed pool of workers goroutines, so that up
+// to workers messages are handled concurrently. It's an alternative to WithUnorderedDelivery, with which paho
+// starts a goroutine per message without limit. Up to workers more messages may wait for a free worker; beyond that
+// the handler blocks, which stops paho reading from the connection until a worker is free.
+//
+// The workers run for the life of the program, so create one PooledHandler per subscription rather than per
+// connection. Messages are acknowledged when they're handed to a worker, not when inner returns, unless
+// WithManualAck is used.
+func PooledHandler(workers int, inner mqtt.MessageHandler) mqtt.MessageHandler {
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan pooledMessage, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for m := range queue {
+				inner(m.client, m.msg)
+			}
+		}()
+	}
+
+	return func(c mqtt.Client, m mqtt.Message) {
+		queue <- pooledMessage{client: c, msg: m}
+	}
+}
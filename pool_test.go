@@ -0,0 +1,39 @@
+package awsiotcore
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestPooledHandler(t *testing.T) {
+	const workers = 3
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	h := PooledHandler(workers, func(c mqtt.Client, m mqtt.Message) {
+		defer wg.Done()
+		n := running.Add(1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+	})
+
+	client := newFakeClient()
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		h(client, &fakeMessage{topic: "things/foo/commands"})
+	}
+	wg.Wait()
+
+	if got := maxRunning.Load(); got < 2 || got > workers {
+		t.Errorf("got up to %d messages handled at once, want 2 to %d", got, workers)
+	}
+}
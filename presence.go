@@ -0,0 +1,61 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// presenceTopicPrefix is the prefix of the topics on which AWS IoT publishes lifecycle events when clients connect
+// and disconnect. See https://docs.aws.amazon.com/iot/latest/developerguide/life-cycle-events.html.
+const presenceTopicPrefix = "$aws/events/presence/"
+
+// PresenceConnectedTopic returns the topic on which AWS IoT publishes an event when the client with the given ID
+// connects. Pass "+" as the client ID to get a filter that matches all clients.
+func PresenceConnectedTopic(clientID string) string {
+	return presenceTopicPrefix + "connected/" + clientID
+}
+
+// PresenceDisconnectedTopic returns the topic on which AWS IoT publishes an event when the client with the given ID
+// disconnects. Pass "+" as the client ID to get a filter that matches all clients.
+func PresenceDisconnectedTopic(clientID string) string {
+	return presenceTopicPrefix + "disconnected/" + clientID
+}
+
+// PresenceEvent is the payload of a connect or disconnect lifecycle event.
+type PresenceEvent struct {
+	ClientID string `json:"clientId"`
+	// Timestamp is the time of the event in milliseconds since the Unix epoch. Use Time to get it as a time.Time.
+	Timestamp int64 `json:"timestamp"`
+	// EventType is "connected" or "disconnected".
+	EventType           string `json:"eventType"`
+	SessionIdentifier   string `json:"sessionIdentifier"`
+	PrincipalIdentifier string `json:"principalIdentifier"`
+	// VersionNumber increases with each connection of the client. Events may arrive out of order, so use it to tell
+	// which is the latest.
+	VersionNumber int64 `json:"versionNumber"`
+	// IPAddress is only set for connected events.
+	IPAddress string `json:"ipAddress,omitempty"`
+	// ClientInitiatedDisconnect and DisconnectReason are only set for disconnected events.
+	ClientInitiatedDisconnect bool   `json:"clientInitiatedDisconnect,omitempty"`
+	DisconnectReason          string `json:"disconnectReason,omitempty"`
+}
+
+// Time returns the time of the event.
+func (e *PresenceEvent) Time() time.Time {
+	return time.UnixMilli(e.Timestamp)
+}
+
+// ParsePresenceEvent parses the payload of a connect or disconnect lifecycle event.
+func ParsePresenceEvent(payload []byte) (*PresenceEvent, error) {
+	var e PresenceEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse presence event: %w", err)
+	}
+
+	if e.EventType != "connected" && e.EventType != "disconnected" {
+		return nil, fmt.Errorf("awsiotcore: presence event has unknown event type %q", e.EventType)
+	}
+
+	return &e, nil
+}
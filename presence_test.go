@@ -0,0 +1,43 @@
+package awsiotcore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPresenceTopics(t *testing.T) {
+	if got, want := PresenceConnectedTopic("foo"), "$aws/events/presence/connected/foo"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := PresenceDisconnectedTopic("+"), "$aws/events/presence/disconnected/+"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParsePresenceEvent(t *testing.T) {
+	payload := []byte(`{
+		"clientId": "foo",
+		"timestamp": 1460065214626,
+		"eventType": "disconnected",
+		"sessionIdentifier": "00000000-0000-0000-0000-000000000000",
+		"principalIdentifier": "000000000000/ABCDEFGHIJKLMNOPQRSTU:some-user/ABCDEFGHIJKLMNOPQRSTU:some-user",
+		"clientInitiatedDisconnect": true,
+		"disconnectReason": "CLIENT_INITIATED_DISCONNECT",
+		"versionNumber": 5
+	}`)
+
+	e, err := ParsePresenceEvent(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.ClientID != "foo" || !e.ClientInitiatedDisconnect || e.DisconnectReason != "CLIENT_INITIATED_DISCONNECT" || e.VersionNumber != 5 {
+		t.Errorf("got %+v", e)
+	}
+	if want := time.Date(2016, 4, 7, 21, 40, 14, 626e6, time.UTC); !e.Time().Equal(want) {
+		t.Errorf("got time %v, want %v", e.Time().UTC(), want)
+	}
+
+	if _, err := ParsePresenceEvent([]byte(`{"eventType":"subscribed"}`)); err == nil {
+		t.Errorf("got nil error for unknown event type, want non-nil")
+	}
+}
@@ -0,0 +1,168 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrUnsupportedQoS is returned by the publish and subscribe helpers when asked to use QoS 2. AWS IoT supports only
+// QoS 0 and 1, and disconnects clients that use QoS 2.
+// See https://docs.aws.amazon.com/iot/latest/developerguide/mqtt.html#mqtt-qos.
+var ErrUnsupportedQoS = errors.New("awsiotcore: AWS IoT supports QoS 0 and 1 only")
+
+// checkQoS returns an error wrapping ErrUnsupportedQoS if qos is not supported by AWS IoT.
+func checkQoS(qos byte) error {
+	if qos > 1 {
+		return fmt.Errorf("%w, got %d", ErrUnsupportedQoS, qos)
+	}
+	return nil
+}
+
+// publishRequest is a message that is about to be published. PublishOptions may modify it.
+type publishRequest struct {
+	// device is the Device publishing the message, or nil if it's not known.
+	device   *Device
+	topic    string
+	qos      byte
+	retained bool
+	value    interface{}
+	codec    Codec
+}
+
+// PublishOption customizes a message published by Publish, PublishJSON, or Device.PublishTelemetry.
+type PublishOption func(*publishRequest) error
+
+// Publish encodes v using codec, publishes it to topic, and waits for the publish to complete. Any given options are
+// applied, in order, before v is encoded.
+func Publish(client mqtt.Client, topic string, qos byte, retained bool, v interface{}, codec Codec, options ...PublishOption) error {
+	return publish(client, &publishRequest{
+		topic:    topic,
+		qos:      qos,
+		retained: retained,
+		value:    v,
+		codec:    codec,
+	}, options)
+}
+
+// PublishJSON is like Publish but always encodes v as JSON.
+func PublishJSON(client mqtt.Client, topic string, qos byte, retained bool, v interface{}, options ...PublishOption) error {
+	return Publish(client, topic, qos, retained, v, JSON, options...)
+}
+
+// PublishTelemetry encodes v as JSON and publishes it to the device's telemetry topic at QoS 1, or the QoS TopicQoS
+// gives for the topic.
+func (d *Device) PublishTelemetry(client mqtt.Client, v interface{}, options ...PublishOption) error {
+	topic := d.TelemetryTopic()
+	return publish(client, &publishRequest{
+		device: d,
+		topic:  topic,
+		qos:    d.topicQoS(topic, 1),
+		value:  v,
+		codec:  JSON,
+	}, options)
+}
+
+func publish(client mqtt.Client, req *publishRequest, options []PublishOption) error {
+	for _, option := range options {
+		if err := option(req); err != nil {
+			return err
+		}
+	}
+
+	if err := checkQoS(req.qos); err != nil {
+		return err
+	}
+
+	payload, err := req.codec.Marshal(req.value)
+	if err != nil {
+		return fmt.Errorf("awsiotcore: failed to encode payload: %w", err)
+	}
+
+	if t := client.Publish(req.topic, req.qos, req.retained, payload); t.Wait() && t.Error() != nil {
+		return fmt.Errorf("awsiotcore: failed to publish to %s: %w", req.topic, t.Error())
+	}
+	return nil
+}
+
+// PublishBytes publishes an already-encoded payload to topic and waits for the publish to complete. It skips the
+// encoding step of Publish, so high-rate publishers can encode into a buffer of their own and avoid allocating one
+// per message. paho holds on to payload until the publish completes, but once PublishBytes returns nil it's done
+// with it and the buffer may be reused, e.g.:
+//
+//	buf.Reset()
+//	json.NewEncoder(&buf).Encode(v)
+//	err := awsiotcore.PublishBytes(client, topic, 1, false, buf.Bytes())
+//
+// If PublishBytes returns an error then paho may still have the payload, e.g. queued for resending, so don't reuse it.
+func PublishBytes(client mqtt.Client, topic string, qos byte, retained bool, payload []byte) error {
+	if err := checkQoS(qos); err != nil {
+		return err
+	}
+
+	if t := client.Publish(topic, qos, retained, payload); t.Wait() && t.Error() != nil {
+		return fmt.Errorf("awsiotcore: failed to publish to %s: %w", topic, t.Error())
+	}
+	return nil
+}
+
+// PublishRetained publishes payload to topic as a retained message at QoS 1 and waits for the publish to complete.
+// The broker keeps the last retained message on each topic and sends it to new subscribers as soon as they subscribe.
+func PublishRetained(client mqtt.Client, topic string, payload []byte) error {
+	return PublishBytes(client, topic, 1, true, payload)
+}
+
+// ClearRetained clears the retained message on topic, if any, by publishing an empty retained message to it at QoS 1
+// and waiting for the publish to complete. The broker deletes the retained message rather than storing the empty
+// one, so new subscribers receive nothing until another retained message is published.
+func ClearRetained(client mqtt.Client, topic string) error {
+	return PublishRetained(client, topic, []byte{})
+}
+
+// PublishConfig publishes payload to the device's config topic as a retained message, so that subscribers always
+// receive the latest config snapshot. It's published at QoS 1, or the QoS TopicQoS gives for the topic.
+func (d *Device) PublishConfig(client mqtt.Client, payload []byte) error {
+	topic := d.ConfigTopic()
+	return PublishBytes(client, topic, d.topicQoS(topic, 1), true, payload)
+}
+
+// topicQoS returns the QoS that TopicQoS gives for topic, or def if no filter in TopicQoS matches it.
+func (d *Device) topicQoS(topic string, def byte) byte {
+	if qos, ok := d.TopicQoS[topic]; ok {
+		return qos
+	}
+
+	best := ""
+	qos := def
+	for filter, q := range d.TopicQoS {
+		if _, ok := TopicParams(filter, topic); !ok {
+			continue
+		}
+		if len(filter) > len(best) || (len(filter) == len(best) && filter < best) {
+			best, qos = filter, q
+		}
+	}
+	return qos
+}
+
+// PublishContext publishes payload to topic and waits for the publish to complete or for ctx to be done, whichever
+// comes first. If ctx is done first then ctx.Err() is returned. Note that the message may still be sent after
+// PublishContext returns, since paho has no way to cancel a publish once it's queued.
+func PublishContext(ctx context.Context, client mqtt.Client, topic string, qos byte, retained bool, payload []byte) error {
+	if err := checkQoS(qos); err != nil {
+		return err
+	}
+
+	t := client.Publish(topic, qos, retained, payload)
+	select {
+	case <-t.Done():
+		if err := t.Error(); err != nil {
+			return fmt.Errorf("awsiotcore: failed to publish to %s: %w", topic, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
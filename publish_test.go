@@ -0,0 +1,167 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type reading struct {
+	Temp float64 `json:"temp" cbor:"temp"`
+}
+
+func TestPublish(t *testing.T) {
+	for name, codec := range map[string]Codec{"json": JSON, "cbor": CBOR} {
+		t.Run(name, func(t *testing.T) {
+			client := newFakeClient()
+			want := reading{Temp: 18.5}
+			if err := Publish(client, "things/foo/telemetry", 1, false, want, codec); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			msgs := client.messages()
+			if len(msgs) != 1 {
+				t.Fatalf("got %d messages, want 1", len(msgs))
+			}
+
+			var got reading
+			if err := codec.Unmarshal(msgs[0].payload, &got); err != nil {
+				t.Fatalf("failed to decode payload: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPublishError(t *testing.T) {
+	client := newFakeClient()
+	client.publishErr = errors.New("not connected")
+
+	if err := PublishJSON(client, "things/foo/telemetry", 1, false, reading{}); !errors.Is(err, client.publishErr) {
+		t.Errorf("got error %v, want %v", err, client.publishErr)
+	}
+}
+
+func TestPublishConfig(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	if err := d.PublishConfig(client, []byte(`{"interval":60}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if msgs[0].topic != "things/foo/config" {
+		t.Errorf("got topic %q, want %q", msgs[0].topic, "things/foo/config")
+	}
+	if !msgs[0].retained {
+		t.Errorf("message is not retained")
+	}
+}
+
+func TestPublishBytes(t *testing.T) {
+	client := newFakeClient()
+	if err := PublishBytes(client, "things/foo/telemetry", 0, false, []byte(`{"temp":18.5}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if string(msgs[0].payload) != `{"temp":18.5}` || msgs[0].qos != 0 || msgs[0].retained {
+		t.Errorf("got %+v, want the payload unchanged at QoS 0, not retained", msgs[0])
+	}
+
+	client.publishErr = errors.New("not connected")
+	if err := PublishBytes(client, "things/foo/telemetry", 0, false, nil); !errors.Is(err, client.publishErr) {
+		t.Errorf("got error %v, want %v", err, client.publishErr)
+	}
+}
+
+func TestTopicQoS(t *testing.T) {
+	d := &Device{
+		DeviceID: "foo",
+		TopicQoS: map[string]byte{
+			"things/+/#":           1,
+			"things/+/telemetry":   0,
+			"things/foo/lifecycle": 1,
+		},
+	}
+	client := newFakeClient()
+	if err := d.PublishTelemetry(client, reading{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.PublishLifecycle(client, LifecycleStartup); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.PublishConfig(client, []byte("{}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]byte{
+		"things/foo/telemetry": 0,
+		"things/foo/lifecycle": 1,
+		"things/foo/config":    1,
+	}
+	for _, m := range client.messages() {
+		if m.qos != want[m.topic] {
+			t.Errorf("%s: got QoS %d, want %d", m.topic, m.qos, want[m.topic])
+		}
+	}
+
+	if got := d.topicQoS("other/topic", 0); got != 0 {
+		t.Errorf("got QoS %d for unmatched topic, want the default 0", got)
+	}
+}
+
+func TestClearRetained(t *testing.T) {
+	client := newFakeClient()
+	if err := ClearRetained(client, "things/foo/config"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if len(msgs[0].payload) != 0 || !msgs[0].retained {
+		t.Errorf("got payload %q, retained %v; want an empty retained message", msgs[0].payload, msgs[0].retained)
+	}
+}
+
+func TestPublishContext(t *testing.T) {
+	client := newFakeClient()
+	if err := PublishContext(context.Background(), client, "things/foo/telemetry", 1, false, []byte("x")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	client.publishHangs = true
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := PublishContext(ctx, client, "things/foo/telemetry", 1, false, []byte("x")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPublishQoS2(t *testing.T) {
+	client := newFakeClient()
+	if err := PublishJSON(client, "things/foo/telemetry", 2, false, reading{}); !errors.Is(err, ErrUnsupportedQoS) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedQoS)
+	}
+	if err := PublishContext(context.Background(), client, "things/foo/telemetry", 2, false, nil); !errors.Is(err, ErrUnsupportedQoS) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedQoS)
+	}
+	if err := PublishBytes(client, "things/foo/telemetry", 2, false, nil); !errors.Is(err, ErrUnsupportedQoS) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedQoS)
+	}
+	if len(client.messages()) != 0 {
+		t.Errorf("got %d messages published, want 0", len(client.messages()))
+	}
+}
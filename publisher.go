@@ -0,0 +1,85 @@
+package awsiotcore
+
+import (
+	"context"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Publisher publishes values sent on a channel, which suits pipelines in which sensor goroutines produce telemetry
+// into a channel rather than calling Publish themselves.
+type Publisher struct {
+	// Topic is the topic to which values are published.
+	Topic string
+	// QoS is the QoS at which values are published.
+	QoS byte
+	// Codec encodes each value.
+	Codec Codec
+	// Interval is the minimum time between publishes. If it's 0 then values are published as fast as they arrive.
+	Interval time.Duration
+	// Options are applied to each value as it's published, as with Publish.
+	Options []PublishOption
+
+	client mqtt.Client
+	device *Device
+}
+
+// NewPublisher returns a Publisher that publishes JSON to the device's telemetry topic at QoS 1, or the QoS TopicQoS
+// gives for the topic. Change its fields before calling Run to publish differently.
+func (d *Device) NewPublisher(client mqtt.Client) *Publisher {
+	topic := d.TelemetryTopic()
+	return &Publisher{
+		Topic:  topic,
+		QoS:    d.topicQoS(topic, 1),
+		Codec:  JSON,
+		client: client,
+		device: d,
+	}
+}
+
+// Run publishes each value received on in, one at a time and in order, until in is closed or ctx is done. It returns
+// nil in the former case and ctx.Err() in the latter. A failure to publish a value doesn't stop Run; the error is
+// sent on errs instead. Sends on errs don't block, so errors are logged and dropped if errs is nil or full.
+func (p *Publisher) Run(ctx context.Context, in <-chan interface{}, errs chan<- error) error {
+	clock := p.device.clockOrSystem()
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case v, ok := <-in:
+			if !ok {
+				return nil
+			}
+
+			if wait := p.Interval - clock.Now().Sub(last); p.Interval > 0 && wait > 0 {
+				t := clock.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return ctx.Err()
+				case <-t.C():
+				}
+			}
+			last = clock.Now()
+
+			err := publish(p.client, &publishRequest{
+				device: p.device,
+				topic:  p.Topic,
+				qos:    p.QoS,
+				value:  v,
+				codec:  p.Codec,
+			}, p.Options)
+			if err == nil {
+				continue
+			}
+
+			select {
+			case errs <- err:
+			default:
+				mqtt.ERROR.Printf("%v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPublisher(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	p := d.NewPublisher(client)
+	p.Interval = 10 * time.Millisecond
+
+	in := make(chan interface{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			in <- reading{Temp: float64(i)}
+		}
+		close(in)
+	}()
+
+	start := time.Now()
+	if err := p.Run(context.Background(), in, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*p.Interval {
+		t.Errorf("published 3 values in %v, want at least %v", elapsed, 2*p.Interval)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3", len(msgs))
+	}
+	for i, m := range msgs {
+		var got reading
+		if err := JSON.Unmarshal(m.payload, &got); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		if m.topic != "things/foo/telemetry" || got.Temp != float64(i) {
+			t.Errorf("message %d: got %v on %s, want temp %d on things/foo/telemetry", i, got, m.topic, i)
+		}
+	}
+}
+
+func TestPublisherErrors(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	client.publishErr = errors.New("not connected")
+	p := d.NewPublisher(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan interface{}, 1)
+	errs := make(chan error, 1)
+	done := make(chan error)
+	go func() {
+		done <- p.Run(ctx, in, errs)
+	}()
+
+	in <- reading{}
+	if err := <-errs; !errors.Is(err, client.publishErr) {
+		t.Errorf("got error %v, want %v", err, client.publishErr)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v from Run, want %v", err, context.Canceled)
+	}
+}
+
+func TestPublisherFakeClock(t *testing.T) {
+	clock := newFakeClock(time.Now())
+	d := &Device{DeviceID: "foo", clock: clock}
+	client := newFakeClient()
+	p := d.NewPublisher(client)
+	p.Interval = time.Minute
+
+	in := make(chan interface{}, 2)
+	in <- reading{Temp: 1}
+	in <- reading{Temp: 2}
+	close(in)
+	done := make(chan error)
+	go func() {
+		done <- p.Run(context.Background(), in, nil)
+	}()
+
+	// The first value is published at once; the second waits out the interval on the fake clock.
+	<-clock.timerMade
+	if n := len(client.messages()); n != 1 {
+		t.Fatalf("got %d messages before the interval passed, want 1", n)
+	}
+	clock.advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(client.messages()); n != 2 {
+		t.Errorf("got %d messages after the interval passed, want 2", n)
+	}
+}
@@ -0,0 +1,160 @@
+package awsiotcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// DefaultShadowTimeout is the default time to wait for AWS IoT to respond to a shadow request.
+const DefaultShadowTimeout = 10 * time.Second
+
+// ShadowDeltaHandler applies desired state to the device. delta is the "state" property of a shadow delta document,
+// that is, the desired properties that differ from reported properties. It may be a subset of the desired state.
+// The handler returns the state to report after applying the delta, which is marshaled to JSON as the shadow's
+// "reported" property. If it returns nil then nothing is reported.
+type ShadowDeltaHandler func(delta json.RawMessage) (reported interface{}, err error)
+
+// ShadowReconciler converges the device's reported state to the desired state in its classic shadow. Each time
+// the desired and reported state differ, AWS IoT publishes a delta; the reconciler passes it to the handler and
+// reports the state the handler returns. If the reported state still differs AWS publishes another delta, so this
+// repeats until desired and reported agree.
+type ShadowReconciler struct {
+	// Timeout is how long to wait for each response from AWS IoT.
+	Timeout time.Duration
+
+	client  mqtt.Client
+	device  *Device
+	handler ShadowDeltaHandler
+}
+
+// NewShadowReconciler returns a ShadowReconciler that passes deltas to handler. The client must already be connected.
+func (d *Device) NewShadowReconciler(client mqtt.Client, handler ShadowDeltaHandler) *ShadowReconciler {
+	return &ShadowReconciler{
+		Timeout: DefaultShadowTimeout,
+		client:  client,
+		device:  d,
+		handler: handler,
+	}
+}
+
+type shadowDelta struct {
+	State   json.RawMessage `json:"state"`
+	Version int             `json:"version"`
+}
+
+// Run reconciles until ctx is done or an error occurs. It first gets the shadow to apply any delta that accrued
+// while it wasn't running, then applies each delta as it's published. An error from the handler or from reporting
+// state stops Run and is returned. When ctx is done Run returns ctx.Err(). As with UpdateShadow, the response
+// topics of the requests it makes are unsubscribed from after each request, as is the delta topic when it returns.
+func (r *ShadowReconciler) Run(ctx context.Context) error {
+	deltaTopic := r.device.ShadowTopic("update/delta")
+	// The handler runs on paho's message router, so it must not block: if a delta is already waiting to be applied
+	// it's replaced by the newer one. Each delta holds every desired property that still differs from the reported
+	// state, so nothing is lost by skipping the older one.
+	deltas := make(chan shadowDelta, 1)
+	handler := func(c mqtt.Client, m mqtt.Message) {
+		var delta shadowDelta
+		if err := json.Unmarshal(m.Payload(), &delta); err != nil {
+			mqtt.ERROR.Printf("awsiotcore: failed to parse shadow delta: %v", err)
+			return
+		}
+
+		for {
+			select {
+			case deltas <- delta:
+				return
+			default:
+			}
+
+			select {
+			case pending := <-deltas:
+				if pending.Version > delta.Version {
+					delta = pending
+				}
+			default:
+			}
+		}
+	}
+	if err := waitSubscribeTimeout(r.client.Subscribe(deltaTopic, 1, handler), r.Timeout); err != nil {
+		return fmt.Errorf("awsiotcore: failed to subscribe to shadow deltas: %w", err)
+	}
+	defer r.client.Unsubscribe(deltaTopic)
+
+	version := 0
+	initial, err := r.currentDelta()
+	if err != nil {
+		return err
+	}
+	if initial != nil {
+		if err := r.apply(initial.State); err != nil {
+			return err
+		}
+		version = initial.Version
+	}
+
+	for {
+		select {
+		case delta := <-deltas:
+			// Deltas can arrive out of order, and the initial get may already have covered this one.
+			if delta.Version <= version {
+				continue
+			}
+			version = delta.Version
+
+			if err := r.apply(delta.State); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// currentDelta gets the shadow and returns its delta, or nil if desired and reported state agree or there is no shadow.
+func (r *ShadowReconciler) currentDelta() (*shadowDelta, error) {
+	token := newClientToken()
+	payload, err := json.Marshal(map[string]string{"clientToken": token})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := r.device.shadowRequest(r.client, "get", token, payload, r.Timeout)
+	var rej *ShadowRejectedError
+	if errors.As(err, &rej) && rej.Code == 404 {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		State struct {
+			Delta json.RawMessage `json:"delta"`
+		} `json:"state"`
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse shadow: %w", err)
+	}
+
+	if len(doc.State.Delta) == 0 {
+		return nil, nil
+	}
+	return &shadowDelta{State: doc.State.Delta, Version: doc.Version}, nil
+}
+
+func (r *ShadowReconciler) apply(delta json.RawMessage) error {
+	reported, err := r.handler(delta)
+	if err != nil {
+		return err
+	}
+	if reported == nil {
+		return nil
+	}
+
+	return r.device.UpdateShadow(r.client, map[string]interface{}{"reported": reported}, r.Timeout)
+}
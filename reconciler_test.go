@@ -0,0 +1,134 @@
+package awsiotcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestShadowReconciler(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	reported := make(chan string, 2)
+	client.respond = func(c *fakeClient, m *fakeMessage) {
+		var req struct {
+			ClientToken string `json:"clientToken"`
+			State       struct {
+				Reported map[string]string `json:"reported"`
+			} `json:"state"`
+		}
+		json.Unmarshal(m.payload, &req)
+
+		switch m.topic {
+		case d.ShadowTopic("get"):
+			c.deliver(d.ShadowTopic("get/accepted"), []byte(fmt.Sprintf(
+				`{"state":{"desired":{"color":"red"},"delta":{"color":"red"}},"version":5,"clientToken":%q}`, req.ClientToken)))
+		case d.ShadowTopic("update"):
+			reported <- req.State.Reported["color"]
+			c.deliver(d.ShadowTopic("update/accepted"), []byte(fmt.Sprintf(`{"clientToken":%q}`, req.ClientToken)))
+		}
+	}
+
+	r := d.NewShadowReconciler(client, func(delta json.RawMessage) (interface{}, error) {
+		var desired map[string]string
+		if err := json.Unmarshal(delta, &desired); err != nil {
+			return nil, err
+		}
+		return desired, nil
+	})
+	r.Timeout = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	if got := <-reported; got != "red" {
+		t.Errorf("got reported color %q from initial get, want %q", got, "red")
+	}
+
+	// A stale delta must be ignored and a newer one applied.
+	client.deliver(d.ShadowTopic("update/delta"), []byte(`{"state":{"color":"green"},"version":4}`))
+	client.deliver(d.ShadowTopic("update/delta"), []byte(`{"state":{"color":"blue"},"version":6}`))
+	if got := <-reported; got != "blue" {
+		t.Errorf("got reported color %q from delta, want %q", got, "blue")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestShadowReconcilerDoesNotBlockRouter(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	client.respond = func(c *fakeClient, m *fakeMessage) {
+		var req struct {
+			ClientToken string `json:"clientToken"`
+		}
+		json.Unmarshal(m.payload, &req)
+
+		switch m.topic {
+		case d.ShadowTopic("get"):
+			c.deliver(d.ShadowTopic("get/accepted"), []byte(fmt.Sprintf(`{"state":{},"version":5,"clientToken":%q}`, req.ClientToken)))
+		case d.ShadowTopic("update"):
+			c.deliver(d.ShadowTopic("update/accepted"), []byte(fmt.Sprintf(`{"clientToken":%q}`, req.ClientToken)))
+		}
+	}
+
+	applying := make(chan string)
+	release := make(chan struct{})
+	r := d.NewShadowReconciler(client, func(delta json.RawMessage) (interface{}, error) {
+		applying <- string(delta)
+		<-release
+		return nil, nil
+	})
+	r.Timeout = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	// Wait for the subscription to the delta topic to be made.
+	deadline := time.Now().Add(time.Second)
+	for {
+		client.mu.Lock()
+		_, ok := client.subs[d.ShadowTopic("update/delta")]
+		client.mu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("not subscribed to shadow deltas")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	client.deliver(d.ShadowTopic("update/delta"), []byte(`{"state":{"n":6},"version":6}`))
+	<-applying
+
+	// While the handler is busy, further deltas must be handled without blocking, and only the newest kept.
+	delivered := make(chan struct{})
+	go func() {
+		for v := 7; v <= 9; v++ {
+			client.deliver(d.ShadowTopic("update/delta"), []byte(fmt.Sprintf(`{"state":{"n":%d},"version":%d}`, v, v)))
+		}
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatalf("delivering deltas blocked while the handler was busy")
+	}
+
+	release <- struct{}{}
+	if got := <-applying; got != `{"n":9}` {
+		t.Errorf("got delta %s after the handler was busy, want the newest, {\"n\":9}", got)
+	}
+	release <- struct{}{}
+}
@@ -0,0 +1,82 @@
+package awsiotcore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// AmazonRootCAURLs are the URLs of the Amazon Trust Services root CA certs. AWS IoT's ATS endpoints currently chain
+// to Amazon Root CA 1 (RSA) or 3 (ECC); 2 and 4 are reserved for future use, so a bundle that includes all four is
+// ready for Amazon to start using them. See https://docs.aws.amazon.com/iot/latest/developerguide/server-authentication.html.
+var AmazonRootCAURLs = []string{
+	"https://www.amazontrust.com/repository/AmazonRootCA1.pem",
+	"https://www.amazontrust.com/repository/AmazonRootCA2.pem",
+	"https://www.amazontrust.com/repository/AmazonRootCA3.pem",
+	"https://www.amazontrust.com/repository/AmazonRootCA4.pem",
+}
+
+// MissingRootCAs fetches the root CA certs at the given URLs and returns those that are not in the CA bundle at
+// caPath. If no URLs are given, AmazonRootCAURLs is used. Run it periodically, or as part of a fleet health check,
+// to find devices whose CACerts would be left behind when Amazon rotates roots. If httpClient is nil then
+// http.DefaultClient is used.
+func MissingRootCAs(ctx context.Context, caPath string, httpClient *http.Client, urls ...string) ([]CertInfo, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if len(urls) == 0 {
+		urls = AmazonRootCAURLs
+	}
+
+	bundle, err := readCertBundle(caPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []CertInfo
+	for _, u := range urls {
+		b, err := fetch(ctx, httpClient, u)
+		if err != nil {
+			return nil, err
+		}
+
+		roots, err := parseCertBundle(b)
+		if err != nil {
+			return nil, fmt.Errorf("awsiotcore: failed to parse root CA from %s: %w", u, err)
+		}
+
+		for _, root := range roots {
+			found := false
+			for _, c := range bundle {
+				if c.Equal(root) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, newCertInfo(root))
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("awsiotcore: failed to fetch %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
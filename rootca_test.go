@@ -0,0 +1,45 @@
+package awsiotcore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMissingRootCAs(t *testing.T) {
+	present := writeTestCert(t, "present", time.Now(), time.Now().Add(time.Hour))
+	absent := writeTestCert(t, "absent", time.Now(), time.Now().Add(time.Hour))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := map[string]string{
+			"/present.pem": present.certPath,
+			"/absent.pem":  absent.certPath,
+		}[r.URL.Path]
+		if path == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Error(err)
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	missing, err := MissingRootCAs(context.Background(), present.certPath, srv.Client(), srv.URL+"/present.pem", srv.URL+"/absent.pem")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(missing) != 1 || missing[0].Subject != "CN=absent" {
+		t.Errorf("got missing %+v, want just CN=absent", missing)
+	}
+
+	if _, err := MissingRootCAs(context.Background(), present.certPath, srv.Client(), srv.URL+"/notfound.pem"); err == nil {
+		t.Errorf("got nil error for missing URL, want non-nil")
+	}
+}
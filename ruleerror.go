@@ -0,0 +1,48 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// IoTRuleError is the message an AWS IoT rule's error action publishes when one of the rule's actions fails. See
+// https://docs.aws.amazon.com/iot/latest/developerguide/rule-error-handling.html.
+type IoTRuleError struct {
+	RuleName          string `json:"ruleName"`
+	Topic             string `json:"topic"`
+	CloudwatchTraceID string `json:"cloudwatchTraceId"`
+	ClientID          string `json:"clientId"`
+	// OriginalPayload is the payload of the message that the rule failed to process.
+	OriginalPayload []byte           `json:"base64OriginalPayload"`
+	Failures        []IoTRuleFailure `json:"failures"`
+}
+
+// IoTRuleFailure describes the failure of one of a rule's actions.
+type IoTRuleFailure struct {
+	FailedAction   string `json:"failedAction"`
+	FailedResource string `json:"failedResource"`
+	ErrorMessage   string `json:"errorMessage"`
+}
+
+// ParseIoTRuleError parses a message published by an AWS IoT rule's error action.
+func ParseIoTRuleError(payload []byte) (*IoTRuleError, error) {
+	var e IoTRuleError
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to parse rule error: %w", err)
+	}
+
+	if e.RuleName == "" {
+		return nil, fmt.Errorf("awsiotcore: rule error has no rule name")
+	}
+
+	return &e, nil
+}
+
+func (e *IoTRuleError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		failures[i] = fmt.Sprintf("%s on %s: %s", f.FailedAction, f.FailedResource, f.ErrorMessage)
+	}
+	return fmt.Sprintf("awsiotcore: rule %s failed to process message on %s: %s", e.RuleName, e.Topic, strings.Join(failures, "; "))
+}
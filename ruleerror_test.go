@@ -0,0 +1,41 @@
+package awsiotcore
+
+import (
+	"testing"
+)
+
+func TestParseIoTRuleError(t *testing.T) {
+	payload := []byte(`{
+  "ruleName": "TestAction",
+  "topic": "testme/action",
+  "cloudwatchTraceId": "7e146a2c-95b5-6caf-98b9-50e3969734c7",
+  "clientId": "iotconsole-1511213971966-0",
+  "base64OriginalPayload": "eyJ0ZW1wIjogMTguMH0=",
+  "failures": [
+    {
+      "failedAction": "S3Action",
+      "failedResource": "us-east-1-s3-verify-user",
+      "errorMessage": "Failed to put S3 object. The error received was The specified bucket does not exist"
+    }
+  ]
+}`)
+
+	e, err := ParseIoTRuleError(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if e.RuleName != "TestAction" || e.Topic != "testme/action" {
+		t.Errorf("got rule %q and topic %q, want %q and %q", e.RuleName, e.Topic, "TestAction", "testme/action")
+	}
+	if got, want := string(e.OriginalPayload), `{"temp": 18.0}`; got != want {
+		t.Errorf("got original payload %q, want %q", got, want)
+	}
+	if len(e.Failures) != 1 || e.Failures[0].FailedAction != "S3Action" {
+		t.Errorf("got failures %+v, want one S3Action failure", e.Failures)
+	}
+
+	if _, err := ParseIoTRuleError([]byte(`{"temp": 18.0}`)); err == nil {
+		t.Errorf("got nil error for non-rule-error payload, want non-nil")
+	}
+}
@@ -0,0 +1,49 @@
+package awsiotcore
+
+import (
+	"context"
+	"fmt"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// disconnectQuiesce is the number of milliseconds to wait for in-flight work to complete when disconnecting.
+const disconnectQuiesce = 250
+
+// Run creates a client for the device with the given options, connects it, and calls setup with it, e.g. to make
+// subscriptions. It then blocks until ctx is done, at which point it disconnects the client and returns nil. If the
+// client can't be created or connected, or setup returns an error, Run disconnects and returns the error.
+//
+// Run takes care of the connect/serve/shutdown lifecycle that most device programs need. Use signal.NotifyContext to
+// run until the process is signaled.
+func Run(ctx context.Context, d *Device, setup func(mqtt.Client) error, options ...func(*Device, *mqtt.ClientOptions) error) error {
+	client, err := d.NewClient(options...)
+	if err != nil {
+		return err
+	}
+	return run(ctx, client, setup)
+}
+
+// run is Run for an already created client.
+func run(ctx context.Context, client mqtt.Client, setup func(mqtt.Client) error) error {
+	token := client.Connect()
+	select {
+	case <-token.Done():
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("awsiotcore: failed to connect: %w", err)
+		}
+	case <-ctx.Done():
+		client.Disconnect(0)
+		return ctx.Err()
+	}
+	defer client.Disconnect(disconnectQuiesce)
+
+	if setup != nil {
+		if err := setup(client); err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+	return nil
+}
@@ -0,0 +1,81 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestRun(t *testing.T) {
+	client := newFakeClient()
+	client.connected = false
+	ctx, cancel := context.WithCancel(context.Background())
+
+	setupDone := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- run(ctx, client, func(c mqtt.Client) error {
+			if !c.IsConnected() {
+				t.Errorf("setup called with a disconnected client")
+			}
+			close(setupDone)
+			return nil
+		})
+	}()
+
+	<-setupDone
+	select {
+	case err := <-errc:
+		t.Fatalf("run returned %v before ctx was done", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-errc; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if client.IsConnected() {
+		t.Errorf("client still connected after run returned")
+	}
+}
+
+func TestRunSetupError(t *testing.T) {
+	client := newFakeClient()
+	errSetup := errors.New("setup failed")
+
+	err := run(context.Background(), client, func(mqtt.Client) error { return errSetup })
+	if !errors.Is(err, errSetup) {
+		t.Errorf("got error %v, want %v", err, errSetup)
+	}
+	if client.IsConnected() {
+		t.Errorf("client still connected after setup failed")
+	}
+}
+
+func TestRunConnectError(t *testing.T) {
+	client := newFakeClient()
+	client.connected = false
+	errConnect := errors.New("connection refused")
+	client.connect = func() error { return errConnect }
+
+	called := false
+	err := run(context.Background(), client, func(mqtt.Client) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, errConnect) {
+		t.Errorf("got error %v, want %v", err, errConnect)
+	}
+	if called {
+		t.Errorf("setup called after connect failed")
+	}
+}
+
+func TestRunInvalidDevice(t *testing.T) {
+	if err := Run(context.Background(), &Device{}, nil); err == nil {
+		t.Errorf("got nil error for invalid device, want non-nil")
+	}
+}
@@ -0,0 +1,133 @@
+package awsiotcore
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrClientClosed is returned by the tokens of publishes made on a SafeClient after it's closed.
+var ErrClientClosed = errors.New("awsiotcore: client closed")
+
+// publishToken is the mqtt.Token returned by SafeClient.Publish.
+type publishToken struct {
+	done chan struct{}
+	err  error
+}
+
+func newPublishToken() *publishToken {
+	return &publishToken{done: make(chan struct{})}
+}
+
+func (t *publishToken) complete(err error) {
+	t.err = err
+	close(t.done)
+}
+
+func (t *publishToken) Wait() bool {
+	<-t.done
+	return true
+}
+
+func (t *publishToken) WaitTimeout(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-t.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (t *publishToken) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *publishToken) Error() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+type queuedPublish struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  interface{}
+	token    *publishToken
+}
+
+// SafeClient wraps an mqtt.Client and serializes publishes through a single goroutine so that goroutines sharing
+// a client can't interleave them unpredictably. It implements mqtt.Client, so it can be used anywhere the wrapped
+// client was; all methods other than Publish go straight to the wrapped client.
+//
+// SafeClient guarantees that publishes are handed to the wrapped client in the order that Publish was called, and
+// that each is handed over only once the previous one has completed (i.e. its token is done). Messages therefore reach
+// the broker in call order regardless of QoS. The price is that only one publish is in flight at a time.
+//
+// Call Close when done with the SafeClient to stop its goroutine. Closing does not disconnect the wrapped client.
+type SafeClient struct {
+	mqtt.Client
+
+	queue chan queuedPublish
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewSafeClient returns a SafeClient that wraps client. queueSize is the number of publishes that may be waiting to
+// be sent before Publish blocks.
+func NewSafeClient(client mqtt.Client, queueSize int) *SafeClient {
+	c := &SafeClient{
+		Client: client,
+		queue:  make(chan queuedPublish, queueSize),
+		done:   make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *SafeClient) run() {
+	defer close(c.done)
+	for p := range c.queue {
+		t := c.Client.Publish(p.topic, p.qos, p.retained, p.payload)
+		t.Wait()
+		p.token.complete(t.Error())
+	}
+}
+
+// Publish queues a message to be published. The returned token completes once the message has been published by
+// the wrapped client.
+func (c *SafeClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	t := newPublishToken()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		t.complete(ErrClientClosed)
+		return t
+	}
+
+	c.queue <- queuedPublish{topic: topic, qos: qos, retained: retained, payload: payload, token: t}
+	return t
+}
+
+// Close stops accepting publishes and waits for those already queued to be sent.
+func (c *SafeClient) Close() {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+	}
+	c.mu.Unlock()
+
+	<-c.done
+}
@@ -0,0 +1,38 @@
+package awsiotcore
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSafeClientOrder(t *testing.T) {
+	inner := newFakeClient()
+	c := NewSafeClient(inner, 10)
+
+	for i := 0; i < 100; i++ {
+		c.Publish("things/foo/telemetry", 1, false, []byte(fmt.Sprint(i)))
+	}
+	c.Close()
+
+	msgs := inner.messages()
+	if len(msgs) != 100 {
+		t.Fatalf("got %d messages, want 100", len(msgs))
+	}
+	for i, m := range msgs {
+		if got, want := string(m.payload), fmt.Sprint(i); got != want {
+			t.Errorf("message %d: got payload %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestSafeClientClosed(t *testing.T) {
+	c := NewSafeClient(newFakeClient(), 1)
+	c.Close()
+
+	token := c.Publish("things/foo/telemetry", 1, false, []byte("x"))
+	token.Wait()
+	if !errors.Is(token.Error(), ErrClientClosed) {
+		t.Errorf("got error %v, want %v", token.Error(), ErrClientClosed)
+	}
+}
@@ -0,0 +1,42 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+// WithSequenceNumbers returns a PublishOption that adds a sequence number to the published value, as the given
+// top-level field of its JSON encoding, so that the backend can detect lost messages by looking for gaps. The value
+// must encode to a JSON object and the message must be encoded as JSON, so apply this option before any that change
+// the codec, e.g. WithGzip.
+//
+// The counter lives in the returned option, so create one per device and pass the same option to every publish. It
+// isn't affected by reconnects. The first message carries sequence number 0, and since the counter starts over when
+// the process restarts, the backend should treat 0 as a restart rather than a gap.
+func WithSequenceNumbers(field string) PublishOption {
+	var next atomic.Uint64
+	return func(req *publishRequest) error {
+		if req.codec != JSON {
+			return fmt.Errorf("awsiotcore: WithSequenceNumbers requires the JSON codec")
+		}
+
+		b, err := json.Marshal(req.value)
+		if err != nil {
+			return fmt.Errorf("awsiotcore: failed to encode payload: %w", err)
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(b, &obj); err != nil || obj == nil {
+			return fmt.Errorf("awsiotcore: WithSequenceNumbers requires a value that encodes to a JSON object")
+		}
+
+		seq, err := json.Marshal(next.Add(1) - 1)
+		if err != nil {
+			return err
+		}
+		obj[field] = seq
+		req.value = obj
+		return nil
+	}
+}
@@ -0,0 +1,43 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWithSequenceNumbers(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	seq := WithSequenceNumbers("seq")
+	for i := 0; i < 3; i++ {
+		if err := d.PublishTelemetry(client, reading{Temp: 18.5}, seq); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for i, msg := range client.messages() {
+		var got struct {
+			Temp float64 `json:"Temp"`
+			Seq  uint64  `json:"seq"`
+		}
+		if err := json.Unmarshal(msg.payload, &got); err != nil {
+			t.Fatalf("failed to parse payload: %v", err)
+		}
+		if got.Seq != uint64(i) || got.Temp != 18.5 {
+			t.Errorf("message %d: got %+v, want seq %d and temp 18.5", i, got, i)
+		}
+	}
+}
+
+func TestWithSequenceNumbersErrors(t *testing.T) {
+	client := newFakeClient()
+	if err := PublishJSON(client, "foo", 1, false, []int{1, 2}, WithSequenceNumbers("seq")); err == nil {
+		t.Errorf("got nil error for a non-object value, want non-nil")
+	}
+	if err := Publish(client, "foo", 1, false, reading{}, CBOR, WithSequenceNumbers("seq")); err == nil {
+		t.Errorf("got nil error for the CBOR codec, want non-nil")
+	}
+	if n := len(client.messages()); n != 0 {
+		t.Errorf("got %d messages, want 0", n)
+	}
+}
@@ -0,0 +1,172 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrShadowVersionConflict is matched by errors.Is when AWS IoT rejects a shadow update because the version in the
+// request doesn't match the shadow's current version.
+var ErrShadowVersionConflict = errors.New("awsiotcore: shadow version conflict")
+
+// ShadowRejectedError is returned when AWS IoT rejects a shadow request. See
+// https://docs.aws.amazon.com/iot/latest/developerguide/device-shadow-document.html#device-shadow-example-error-json.
+type ShadowRejectedError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ShadowRejectedError) Error() string {
+	return fmt.Sprintf("awsiotcore: shadow request rejected: %d: %s", e.Code, e.Message)
+}
+
+// Is reports whether target is ErrShadowVersionConflict and e is a version conflict.
+func (e *ShadowRejectedError) Is(target error) bool {
+	return target == ErrShadowVersionConflict && e.Code == 409
+}
+
+// ShadowTopic returns the topic for the given operation on the device's classic shadow, e.g. "update" or
+// "update/accepted". See https://docs.aws.amazon.com/iot/latest/developerguide/reserved-topics.html#reserved-topics-shadow.
+func (d *Device) ShadowTopic(operation string) string {
+	return d.bridgeTopic(fmt.Sprintf("$aws/things/%s/shadow/%s", d.ID(), operation))
+}
+
+// RequestShadow asks AWS IoT for the device's classic shadow by publishing an empty message to the shadow get topic.
+// The shadow document is published in response to get/accepted, or an error to get/rejected, so subscribe to those
+// topics (see ShadowTopic) before calling it.
+func (d *Device) RequestShadow(client mqtt.Client) error {
+	topic := d.ShadowTopic("get")
+	if t := client.Publish(topic, d.topicQoS(topic, 1), false, []byte{}); t.Wait() && t.Error() != nil {
+		return fmt.Errorf("awsiotcore: failed to publish to %s: %w", topic, t.Error())
+	}
+	return nil
+}
+
+type shadowUpdate struct {
+	State       interface{} `json:"state"`
+	Version     int         `json:"version,omitempty"`
+	ClientToken string      `json:"clientToken"`
+}
+
+// UpdateShadow updates the device's classic shadow and waits up to timeout for AWS IoT to accept it. state is
+// marshaled to JSON as the "state" property of the update, so it should have "desired" and/or "reported" properties.
+//
+// It subscribes to the update/accepted and update/rejected topics for the duration of the request and unsubscribes
+// when it's done, which also removes any subscription the application has to those topics. An application that
+// needs them should subscribe to a wildcard filter instead, e.g. ShadowTopic("update/+"), which isn't affected.
+func (d *Device) UpdateShadow(client mqtt.Client, state interface{}, timeout time.Duration) error {
+	return d.updateShadow(client, shadowUpdate{State: state}, timeout)
+}
+
+// UpdateShadowIfVersion is like UpdateShadow but the update is only applied if the shadow's current version is
+// version. If it isn't, the returned error matches ErrShadowVersionConflict with errors.Is. This allows multiple
+// writers to update a shadow without clobbering each other's changes. Shadow versions start at 1, so version must
+// be at least 1.
+func (d *Device) UpdateShadowIfVersion(client mqtt.Client, state interface{}, version int, timeout time.Duration) error {
+	if version < 1 {
+		// A zero version would be omitted from the update, making it unconditional.
+		return fmt.Errorf("awsiotcore: shadow version must be at least 1, got %d", version)
+	}
+	return d.updateShadow(client, shadowUpdate{State: state, Version: version}, timeout)
+}
+
+func (d *Device) updateShadow(client mqtt.Client, update shadowUpdate, timeout time.Duration) error {
+	update.ClientToken = newClientToken()
+	b, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("awsiotcore: failed to marshal shadow update: %w", err)
+	}
+
+	_, err = d.shadowRequest(client, "update", update.ClientToken, b, timeout)
+	return err
+}
+
+// Ping measures the round-trip time to AWS IoT by requesting the device's classic shadow and timing the response,
+// from just before the request is published until the response arrives. This covers the whole application-layer
+// path, which makes it more telling than a TCP or ICMP ping when a connection is sluggish. A rejection because the
+// device has no shadow is a response like any other, so Ping works for devices that don't use shadows; the
+// subscriptions to the get response topics are made before timing starts.
+//
+// It must not be called concurrently with other shadow get requests, e.g. concurrent calls to Ping. Like
+// UpdateShadow, it unsubscribes from the get response topics when it's done.
+func (d *Device) Ping(client mqtt.Client, timeout time.Duration) (time.Duration, error) {
+	clientToken := newClientToken()
+	b, err := json.Marshal(struct {
+		ClientToken string `json:"clientToken"`
+	}{clientToken})
+	if err != nil {
+		return 0, fmt.Errorf("awsiotcore: failed to marshal shadow get: %w", err)
+	}
+
+	_, rtt, err := d.shadowRoundTrip(client, "get", clientToken, b, timeout)
+	var rej *ShadowRejectedError
+	if errors.As(err, &rej) && rej.Code == 404 {
+		return rtt, nil
+	}
+	return rtt, err
+}
+
+// shadowRequest publishes payload to the shadow topic for the given operation and waits up to timeout for the
+// accepted or rejected response with the given client token. It returns the accepted response's payload.
+//
+// It subscribes to the response topics for the duration of the request, so requests for the same operation must
+// not be made concurrently, and unsubscribes from them afterwards, even if the application was subscribed to them
+// before.
+func (d *Device) shadowRequest(client mqtt.Client, operation, clientToken string, payload []byte, timeout time.Duration) ([]byte, error) {
+	b, _, err := d.shadowRoundTrip(client, operation, clientToken, payload, timeout)
+	return b, err
+}
+
+// shadowRoundTrip is like shadowRequest but also returns the time from just before the request was published until
+// the response arrived, which is zero if no response arrived. A rejected request has a round-trip time since it
+// got a response.
+func (d *Device) shadowRoundTrip(client mqtt.Client, operation, clientToken string, payload []byte, timeout time.Duration) ([]byte, time.Duration, error) {
+	accepted := d.ShadowTopic(operation + "/accepted")
+	rejected := d.ShadowTopic(operation + "/rejected")
+
+	responses := make(chan mqtt.Message, 1)
+	handler := func(c mqtt.Client, m mqtt.Message) {
+		var resp struct {
+			ClientToken string `json:"clientToken"`
+		}
+		if err := json.Unmarshal(m.Payload(), &resp); err != nil || resp.ClientToken != clientToken {
+			return
+		}
+
+		select {
+		case responses <- m:
+		default:
+		}
+	}
+
+	filters := map[string]byte{accepted: 1, rejected: 1}
+	if err := waitSubscribeTimeout(client.SubscribeMultiple(filters, handler), timeout); err != nil {
+		return nil, 0, fmt.Errorf("awsiotcore: failed to subscribe to shadow %s responses: %w", operation, err)
+	}
+	defer client.Unsubscribe(accepted, rejected)
+
+	start := time.Now()
+	topic := d.ShadowTopic(operation)
+	if err := waitToken(client.Publish(topic, d.topicQoS(topic, 1), false, payload), timeout); err != nil {
+		return nil, 0, fmt.Errorf("awsiotcore: failed to publish shadow %s: %w", operation, err)
+	}
+
+	select {
+	case m := <-responses:
+		rtt := time.Since(start)
+		if m.Topic() == rejected {
+			var rej ShadowRejectedError
+			if err := json.Unmarshal(m.Payload(), &rej); err != nil {
+				return nil, rtt, fmt.Errorf("awsiotcore: failed to parse shadow %s rejection: %w", operation, err)
+			}
+			return nil, rtt, &rej
+		}
+		return m.Payload(), rtt, nil
+	case <-time.After(timeout):
+		return nil, 0, fmt.Errorf("awsiotcore: timed out after %v waiting for shadow %s response", timeout, operation)
+	}
+}
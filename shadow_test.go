@@ -0,0 +1,144 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeShadow returns a fakeClient responder that accepts shadow updates whose version, if given, matches version.
+func fakeShadow(d *Device, version int) func(c *fakeClient, m *fakeMessage) {
+	return func(c *fakeClient, m *fakeMessage) {
+		if m.topic != d.ShadowTopic("update") {
+			return
+		}
+
+		var req struct {
+			Version     int    `json:"version"`
+			ClientToken string `json:"clientToken"`
+		}
+		json.Unmarshal(m.payload, &req)
+
+		if req.Version != 0 && req.Version != version {
+			c.deliver(d.ShadowTopic("update/rejected"),
+				[]byte(fmt.Sprintf(`{"code":409,"message":"Version conflict","clientToken":%q}`, req.ClientToken)))
+			return
+		}
+		c.deliver(d.ShadowTopic("update/accepted"),
+			[]byte(fmt.Sprintf(`{"version":%d,"clientToken":%q}`, version+1, req.ClientToken)))
+	}
+}
+
+func TestShadowTopic(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	want := "$aws/things/foo/shadow/update/delta"
+	if got := d.ShadowTopic("update/delta"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUpdateShadowIfVersion(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	state := map[string]interface{}{"reported": map[string]int{"temp": 18}}
+
+	cases := []struct {
+		name     string
+		version  int
+		conflict bool
+	}{
+		{"matching_version", 3, false},
+		{"conflicting_version", 2, true},
+	}
+
+	if err := d.UpdateShadowIfVersion(newFakeClient(), state, 0, time.Second); err == nil {
+		t.Errorf("got nil error for version 0, want non-nil")
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := newFakeClient()
+			client.respond = fakeShadow(d, 3)
+
+			err := d.UpdateShadowIfVersion(client, state, c.version, time.Second)
+			if c.conflict {
+				if !errors.Is(err, ErrShadowVersionConflict) {
+					t.Errorf("got error %v, want %v", err, ErrShadowVersionConflict)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequestShadow(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	if err := d.RequestShadow(client); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msgs := client.messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if msgs[0].topic != "$aws/things/foo/shadow/get" {
+		t.Errorf("got topic %q, want %q", msgs[0].topic, "$aws/things/foo/shadow/get")
+	}
+	if len(msgs[0].payload) != 0 {
+		t.Errorf("got payload %q, want empty", msgs[0].payload)
+	}
+}
+
+func TestPing(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+
+	cases := []struct {
+		name     string
+		response string
+		wantErr  bool
+	}{
+		{"accepted", `{"state":{},"clientToken":%q}`, false},
+		{"no_shadow", `{"code":404,"message":"No shadow exists with name: 'foo'","clientToken":%q}`, false},
+		{"rejected", `{"code":403,"message":"Forbidden","clientToken":%q}`, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := newFakeClient()
+			client.respond = func(fc *fakeClient, m *fakeMessage) {
+				if m.topic != d.ShadowTopic("get") {
+					return
+				}
+				var req struct {
+					ClientToken string `json:"clientToken"`
+				}
+				json.Unmarshal(m.payload, &req)
+
+				topic := d.ShadowTopic("get/accepted")
+				if strings.Contains(c.response, "code") {
+					topic = d.ShadowTopic("get/rejected")
+				}
+				time.Sleep(10 * time.Millisecond)
+				fc.deliver(topic, []byte(fmt.Sprintf(c.response, req.ClientToken)))
+			}
+
+			rtt, err := d.Ping(client, time.Second)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("got nil error, want non-nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rtt < 10*time.Millisecond || rtt > time.Second {
+				t.Errorf("got round-trip time %v, want between 10ms and 1s", rtt)
+			}
+		})
+	}
+}
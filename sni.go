@@ -0,0 +1,127 @@
+package awsiotcore
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// WithoutSNI returns an option that connects without sending TLS Server Name Indication. AWS IoT requires SNI, so
+// this is only useful for other brokers, e.g. self-hosted ones that reject or misroute connections naming the
+// endpoint. The broker's cert is still verified against the server name NewClient sets, the device's endpoint, and
+// against CACerts, or whatever WithTLSConfig changed them to.
+//
+// paho's TLS dialer always sends the broker's host name as SNI, so the option opens connections itself. Like
+// WithPinnedDNS it only supports TLS brokers (schemes ssl, tls, mqtts and tcps), not WebSockets or proxies, and it
+// can't be combined with WithPinnedDNS, WithConnectAttemptHandler or options built on it, like WithFallbackEndpoint;
+// whichever of them comes second returns an error.
+func WithoutSNI() func(*Device, *mqtt.ClientOptions) error {
+	return func(d *Device, opts *mqtt.ClientOptions) error {
+		if opts.OnConnectAttempt != nil {
+			return fmt.Errorf("awsiotcore: WithoutSNI can't be combined with a connect attempt handler")
+		}
+		if opts.CustomOpenConnectionFn != nil {
+			return fmt.Errorf("awsiotcore: WithoutSNI can't be combined with WithPinnedDNS")
+		}
+
+		opts.SetCustomOpenConnectionFn(dialWithoutSNI)
+		return nil
+	}
+}
+
+// dialWithoutSNI opens a TLS connection to uri without SNI, verifying the broker's cert against the configured server
+// name or, if there is none, uri's host.
+func dialWithoutSNI(uri *url.URL, options mqtt.ClientOptions) (net.Conn, error) {
+	switch uri.Scheme {
+	case "ssl", "tls", "mqtts", "tcps":
+	default:
+		return nil, fmt.Errorf("awsiotcore: WithoutSNI does not support scheme %q", uri.Scheme)
+	}
+
+	dialer := options.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: options.ConnectTimeout}
+	}
+
+	tlsConf := &tls.Config{}
+	if options.TLSConfig != nil {
+		tlsConf = options.TLSConfig.Clone()
+	}
+	name := tlsConf.ServerName
+	if name == "" {
+		name = uri.Hostname()
+	}
+
+	// crypto/tls sends ServerName as SNI and also verifies the cert against it, so clear it and verify here instead.
+	skipVerify := tlsConf.InsecureSkipVerify
+	verifyPeer := tlsConf.VerifyPeerCertificate
+	verifyConn := tlsConf.VerifyConnection
+	tlsConf.ServerName = ""
+	tlsConf.InsecureSkipVerify = true
+	tlsConf.VerifyPeerCertificate = nil
+	tlsConf.VerifyConnection = func(cs tls.ConnectionState) error {
+		var chains [][]*x509.Certificate
+		if !skipVerify {
+			var err error
+			if chains, err = verifyServerCert(cs.PeerCertificates, name, tlsConf.RootCAs); err != nil {
+				return err
+			}
+		}
+		if verifyPeer != nil {
+			rawCerts := make([][]byte, len(cs.PeerCertificates))
+			for i, cert := range cs.PeerCertificates {
+				rawCerts[i] = cert.Raw
+			}
+			if err := verifyPeer(rawCerts, chains); err != nil {
+				return err
+			}
+		}
+		if verifyConn != nil {
+			cs.ServerName = name
+			cs.VerifiedChains = chains
+			return verifyConn(cs)
+		}
+		return nil
+	}
+
+	conn, err := dialer.Dial("tcp", uri.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if dialer.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dialer.Timeout)
+		defer cancel()
+	}
+	tlsConn := tls.Client(conn, tlsConf)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// verifyServerCert verifies certs, the chain a TLS server presented, for name against roots, or the system roots if
+// roots is nil, as crypto/tls does, and returns the verified chains.
+func verifyServerCert(certs []*x509.Certificate, name string, roots *x509.CertPool) ([][]*x509.Certificate, error) {
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("awsiotcore: broker presented no certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	return certs[0].Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+}
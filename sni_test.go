@@ -0,0 +1,148 @@
+package awsiotcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// sniListener starts a TLS server with the given cert on localhost. The server name sent in each ClientHello is
+// reported on the returned channel.
+func sniListener(t *testing.T, cert testCert) (*url.URL, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	names := make(chan string, 1)
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert.cert.Raw}, PrivateKey: cert.key}},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			names <- hello.ServerName
+			return nil, nil
+		},
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				tls.Server(conn, conf).Handshake()
+			}()
+		}
+	}()
+
+	// Dial by host name rather than IP, since Go's TLS client never sends an IP as SNI.
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	return &url.URL{Scheme: "ssl", Host: net.JoinHostPort("localhost", port)}, names
+}
+
+func TestWithoutSNI(t *testing.T) {
+	cert := writeTestCert(t, "broker", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	broker, names := sniListener(t, cert)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.cert)
+
+	d := &Device{Endpoint: "broker.example.com", DeviceID: "foo"}
+	opts := testOpts(d)
+	opts.TLSConfig.RootCAs = roots
+	if err := WithoutSNI()(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	conn, err := opts.CustomOpenConnectionFn(broker, *opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+	if got := <-names; got != "" {
+		t.Errorf("got SNI %q, want none", got)
+	}
+	if opts.TLSConfig.ServerName != d.Endpoint {
+		t.Errorf("got ServerName %q, want it left as %q", opts.TLSConfig.ServerName, d.Endpoint)
+	}
+}
+
+func TestWithoutSNIVerifiesServerName(t *testing.T) {
+	cert := writeTestCert(t, "broker", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	broker, names := sniListener(t, cert)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.cert)
+
+	d := &Device{Endpoint: "other.example.com", DeviceID: "foo"}
+	opts := testOpts(d)
+	opts.TLSConfig.RootCAs = roots
+	if err := WithoutSNI()(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var hostErr x509.HostnameError
+	if _, err := opts.CustomOpenConnectionFn(broker, *opts); !errors.As(err, &hostErr) {
+		t.Errorf("got error %v, want a HostnameError", err)
+	}
+	<-names
+
+	// Without the broker's cert in RootCAs the chain doesn't verify either.
+	d.Endpoint = "broker.example.com"
+	opts = testOpts(d)
+	opts.TLSConfig.RootCAs = x509.NewCertPool()
+	if err := WithoutSNI()(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var authErr x509.UnknownAuthorityError
+	if _, err := opts.CustomOpenConnectionFn(broker, *opts); !errors.As(err, &authErr) {
+		t.Errorf("got error %v, want an UnknownAuthorityError", err)
+	}
+}
+
+func TestWithoutSNIUnsupportedScheme(t *testing.T) {
+	d := &Device{Endpoint: "broker.example.com", DeviceID: "foo"}
+	opts := testOpts(d)
+	if err := WithoutSNI()(d, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := opts.CustomOpenConnectionFn(&url.URL{Scheme: "wss", Host: "broker.example.com:443"}, *opts); err == nil {
+		t.Errorf("got nil error for wss broker, want non-nil")
+	}
+}
+
+func TestWithoutSNIConflicts(t *testing.T) {
+	// The .invalid TLD is guaranteed never to resolve, so WithPinnedDNS's initial lookup fails fast.
+	d := &Device{Endpoint: "abc123-ats.iot.example.invalid", DeviceID: "foo"}
+	handler := WithConnectAttemptHandler(func(broker *url.URL, tlsCfg *tls.Config) *tls.Config { return tlsCfg })
+
+	cases := []struct {
+		name          string
+		first, second func(*Device, *mqtt.ClientOptions) error
+	}{
+		{"WithPinnedDNS first", WithPinnedDNS(time.Hour), WithoutSNI()},
+		{"WithoutSNI before WithPinnedDNS", WithoutSNI(), WithPinnedDNS(time.Hour)},
+		{"WithConnectAttemptHandler first", handler, WithoutSNI()},
+		{"WithoutSNI before WithConnectAttemptHandler", WithoutSNI(), handler},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := testOpts(d)
+			if err := c.first(d, opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := c.second(d, opts); err == nil {
+				t.Errorf("got nil error, want non-nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package awsiotcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	// DefaultStreamBlockSize is the default number of bytes requested per block by a StreamReader.
+	DefaultStreamBlockSize = 4096
+
+	// DefaultStreamTimeout is the default time a StreamReader waits for each response from AWS IoT.
+	DefaultStreamTimeout = 30 * time.Second
+)
+
+// streamDescription is the payload AWS IoT publishes in response to a DescribeStream request.
+type streamDescription struct {
+	ClientToken string `json:"c"`
+	Files       []struct {
+		ID   int `json:"f"`
+		Size int `json:"z"`
+	} `json:"r"`
+}
+
+// streamGetRequest is the payload of a GetStream request.
+type streamGetRequest struct {
+	ClientToken string `json:"c"`
+	FileID      int    `json:"f"`
+	BlockSize   int    `json:"l"`
+	BlockOffset int    `json:"o"`
+	NumBlocks   int    `json:"n"`
+}
+
+// streamData is the payload AWS IoT publishes in response to a GetStream request.
+type streamData struct {
+	ClientToken string `json:"c"`
+	FileID      int    `json:"f"`
+	BlockID     int    `json:"i"`
+	Payload     []byte `json:"p"`
+}
+
+// streamRejected is the payload AWS IoT publishes when it rejects a stream request.
+type streamRejected struct {
+	ClientToken string `json:"c"`
+	Code        string `json:"o"`
+	Message     string `json:"m"`
+}
+
+// StreamRejectedError is returned by a StreamReader when AWS IoT rejects one of its requests.
+type StreamRejectedError struct {
+	Code    string
+	Message string
+}
+
+func (e *StreamRejectedError) Error() string {
+	return fmt.Sprintf("awsiotcore: stream request rejected: %s: %s", e.Code, e.Message)
+}
+
+// StreamReader reads a file from an AWS IoT MQTT-based file delivery stream. It requests the file one block at a time
+// over MQTT and exposes the reassembled file as an io.Reader. See
+// https://docs.aws.amazon.com/iot/latest/developerguide/mqtt-based-file-delivery.html.
+//
+// BlockSize and Timeout may be changed before the first call to Read. Call Close when done to unsubscribe from the
+// stream's topics.
+type StreamReader struct {
+	// BlockSize is the number of bytes to request per block. AWS IoT accepts 256 to 131072.
+	BlockSize int
+	// Timeout is how long to wait for each response from AWS IoT.
+	Timeout time.Duration
+
+	client   mqtt.Client
+	device   *Device
+	streamID string
+	fileID   int
+	token    string
+
+	// subscribed is set once the response subscriptions are made, and started once the file's size is known.
+	subscribed bool
+	started    bool
+	size       int
+	nextBlock  int
+	read       int
+	buf        []byte
+
+	responses chan mqtt.Message
+}
+
+// NewStreamReader returns a StreamReader that reads the file with the given ID from the given stream. The client must
+// already be connected.
+func (d *Device) NewStreamReader(client mqtt.Client, streamID string, fileID int) *StreamReader {
+	return &StreamReader{
+		BlockSize: DefaultStreamBlockSize,
+		Timeout:   DefaultStreamTimeout,
+		client:    client,
+		device:    d,
+		streamID:  streamID,
+		fileID:    fileID,
+		token:     newClientToken(),
+		responses: make(chan mqtt.Message, 8),
+	}
+}
+
+// StreamTopic returns the topic for the given operation on the given stream, e.g. "get" or "data". The JSON payload
+// format is always used.
+func (d *Device) StreamTopic(streamID, operation string) string {
+	return d.bridgeTopic(fmt.Sprintf("$aws/things/%s/streams/%s/%s/json", d.ID(), streamID, operation))
+}
+
+func (r *StreamReader) topic(operation string) string {
+	return r.device.StreamTopic(r.streamID, operation)
+}
+
+// Size returns the size of the file in bytes. It's only known after the first call to Read, before which it's 0.
+func (r *StreamReader) Size() int {
+	return r.size
+}
+
+// Read reads up to len(p) bytes of the file into p, requesting more blocks from AWS IoT as needed.
+func (r *StreamReader) Read(p []byte) (int, error) {
+	if !r.started {
+		if err := r.start(); err != nil {
+			return 0, err
+		}
+	}
+
+	for len(r.buf) == 0 {
+		if r.read >= r.size {
+			return 0, io.EOF
+		}
+
+		if err := r.fetchBlock(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close unsubscribes from the stream's topics.
+func (r *StreamReader) Close() error {
+	if !r.subscribed {
+		return nil
+	}
+	return waitToken(r.client.Unsubscribe(r.topic("data"), r.topic("description"), r.topic("rejected")), r.Timeout)
+}
+
+// start subscribes to the stream's response topics, if it hasn't already, and describes the stream to learn the
+// file's size. If it fails then the next Read calls it again, so that a failed describe isn't mistaken for an empty
+// file.
+func (r *StreamReader) start() error {
+	if !r.subscribed {
+		if err := r.subscribe(); err != nil {
+			return err
+		}
+	}
+
+	m, err := r.request("describe", map[string]string{"c": r.token}, "description")
+	if err != nil {
+		return err
+	}
+
+	var desc streamDescription
+	if err := json.Unmarshal(m.Payload(), &desc); err != nil {
+		return fmt.Errorf("awsiotcore: failed to parse stream description: %w", err)
+	}
+
+	for _, f := range desc.Files {
+		if f.ID == r.fileID {
+			r.size = f.Size
+			r.started = true
+			return nil
+		}
+	}
+	return fmt.Errorf("awsiotcore: stream %q has no file with ID %d", r.streamID, r.fileID)
+}
+
+// subscribe subscribes to the stream's response topics.
+func (r *StreamReader) subscribe() error {
+	filters := map[string]byte{
+		r.topic("data"):        1,
+		r.topic("description"): 1,
+		r.topic("rejected"):    1,
+	}
+	// Only one request is outstanding at a time, so if the channel is full the message is a stray duplicate. Drop it
+	// rather than block the client's message handling.
+	handler := func(c mqtt.Client, m mqtt.Message) {
+		select {
+		case r.responses <- m:
+		default:
+		}
+	}
+	if err := waitSubscribeTimeout(r.client.SubscribeMultiple(filters, handler), r.Timeout); err != nil {
+		return fmt.Errorf("awsiotcore: failed to subscribe to stream: %w", err)
+	}
+	r.subscribed = true
+	return nil
+}
+
+// fetchBlock requests the next block of the file and appends it to the buffer.
+func (r *StreamReader) fetchBlock() error {
+	req := streamGetRequest{
+		ClientToken: r.token,
+		FileID:      r.fileID,
+		BlockSize:   r.BlockSize,
+		BlockOffset: r.nextBlock,
+		NumBlocks:   1,
+	}
+
+	for {
+		m, err := r.request("get", req, "data")
+		if err != nil {
+			return err
+		}
+
+		var data streamData
+		if err := json.Unmarshal(m.Payload(), &data); err != nil {
+			return fmt.Errorf("awsiotcore: failed to parse stream data: %w", err)
+		}
+
+		// A retried or duplicated response for an earlier block may arrive; ignore it and ask again.
+		if data.FileID != r.fileID || data.BlockID != r.nextBlock {
+			continue
+		}
+
+		r.nextBlock++
+		r.read += len(data.Payload)
+		r.buf = append(r.buf, data.Payload...)
+		return nil
+	}
+}
+
+// request publishes payload to the topic for the given operation and waits for a response on the topic for the
+// given response operation, or on the rejected topic.
+func (r *StreamReader) request(operation string, payload interface{}, response string) (mqtt.Message, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	topic := r.topic(operation)
+	if err := waitToken(r.client.Publish(topic, r.device.topicQoS(topic, 1), false, b), r.Timeout); err != nil {
+		return nil, fmt.Errorf("awsiotcore: failed to publish stream request: %w", err)
+	}
+
+	timeout := time.After(r.Timeout)
+	for {
+		select {
+		case m := <-r.responses:
+			switch m.Topic() {
+			case r.topic(response):
+				return m, nil
+			case r.topic("rejected"):
+				var rej streamRejected
+				if err := json.Unmarshal(m.Payload(), &rej); err != nil {
+					return nil, fmt.Errorf("awsiotcore: failed to parse stream rejection: %w", err)
+				}
+				if rej.ClientToken == "" || rej.ClientToken == r.token {
+					return nil, &StreamRejectedError{Code: rej.Code, Message: rej.Message}
+				}
+			}
+		case <-timeout:
+			return nil, fmt.Errorf("awsiotcore: timed out after %v waiting for stream %s response", r.Timeout, response)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package awsiotcore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStream returns a fakeClient responder that serves file as file ID 1 of a stream.
+func fakeStream(d *Device, streamID string, file []byte) func(c *fakeClient, m *fakeMessage) {
+	return func(c *fakeClient, m *fakeMessage) {
+		switch m.topic {
+		case d.StreamTopic(streamID, "describe"):
+			b, _ := json.Marshal(map[string]interface{}{
+				"r": []map[string]int{{"f": 1, "z": len(file)}},
+			})
+			c.deliver(d.StreamTopic(streamID, "description"), b)
+		case d.StreamTopic(streamID, "get"):
+			var req streamGetRequest
+			json.Unmarshal(m.payload, &req)
+			if req.FileID != 1 {
+				b, _ := json.Marshal(streamRejected{Code: "ResourceNotFound", Message: "no such file"})
+				c.deliver(d.StreamTopic(streamID, "rejected"), b)
+				return
+			}
+
+			start := req.BlockOffset * req.BlockSize
+			end := start + req.BlockSize
+			if end > len(file) {
+				end = len(file)
+			}
+			b, _ := json.Marshal(streamData{FileID: req.FileID, BlockID: req.BlockOffset, Payload: file[start:end]})
+			c.deliver(d.StreamTopic(streamID, "data"), b)
+		}
+	}
+}
+
+func TestStreamReader(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	file := []byte(strings.Repeat("0123456789", 100))
+
+	client := newFakeClient()
+	client.respond = fakeStream(d, "mystream", file)
+
+	r := d.NewStreamReader(client, "mystream", 1)
+	r.BlockSize = 256
+	r.Timeout = time.Second
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, file) {
+		t.Errorf("got %d bytes, want %d bytes matching the file", len(got), len(file))
+	}
+	if r.Size() != len(file) {
+		t.Errorf("got size %d, want %d", r.Size(), len(file))
+	}
+
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error from Close: %v", err)
+	}
+	if len(client.subs) != 0 {
+		t.Errorf("got %d subscriptions after Close, want 0", len(client.subs))
+	}
+}
+
+func TestStreamReaderMissingFile(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	client.respond = fakeStream(d, "mystream", []byte("data"))
+
+	r := d.NewStreamReader(client, "mystream", 2)
+	r.Timeout = time.Second
+	if _, err := io.ReadAll(r); err == nil {
+		t.Errorf("got nil error, want non-nil")
+	}
+}
+
+func TestStreamReaderRejected(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	client.respond = func(c *fakeClient, m *fakeMessage) {
+		b, _ := json.Marshal(streamRejected{Code: "Unauthorized", Message: "nope"})
+		c.deliver(d.StreamTopic("mystream", "rejected"), b)
+	}
+
+	r := d.NewStreamReader(client, "mystream", 1)
+	r.Timeout = time.Second
+	_, err := io.ReadAll(r)
+
+	var rej *StreamRejectedError
+	if !errors.As(err, &rej) {
+		t.Fatalf("got error %v, want a *StreamRejectedError", err)
+	}
+	if rej.Code != "Unauthorized" {
+		t.Errorf("got code %q, want %q", rej.Code, "Unauthorized")
+	}
+}
+
+func TestStreamReaderDescribeFailsTwice(t *testing.T) {
+	d := &Device{DeviceID: "foo"}
+	client := newFakeClient()
+	client.respond = fakeStream(d, "mystream", []byte("data"))
+
+	r := d.NewStreamReader(client, "mystream", 2)
+	r.Timeout = time.Second
+	buf := make([]byte, 16)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Read(buf); err == nil || err == io.EOF {
+			t.Errorf("Read %d: got error %v, want the describe error", i, err)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Errorf("unexpected error from Close: %v", err)
+	}
+	if len(client.subs) != 0 {
+		t.Errorf("got %d subscriptions after Close, want 0", len(client.subs))
+	}
+}
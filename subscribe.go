@@ -0,0 +1,289 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// subackFailure is the return code in a SUBACK for a subscription that the broker rejected.
+const subackFailure = 0x80
+
+// SubscriptionError is returned by this package's subscribe helpers when the broker rejects a subscription. AWS IoT
+// does so when, for example, the device's policy doesn't allow iot:Subscribe on the filter. paho reports such a
+// subscription as succeeded, so without this check messages just never arrive.
+type SubscriptionError struct {
+	Filter string
+}
+
+func (e *SubscriptionError) Error() string {
+	return fmt.Sprintf("awsiotcore: broker rejected subscription to %s", e.Filter)
+}
+
+// QoSDowngradeError is returned by Subscribe and SubscribeWithParams when the broker grants a lower QoS than was
+// requested. The subscription is in place, but messages are delivered at no more than the granted QoS, so a
+// subscriber that relies on QoS 1 delivery may miss messages. Callers that accept the downgrade can check for it
+// with errors.As and carry on.
+type QoSDowngradeError struct {
+	Filter    string
+	Requested byte
+	Granted   byte
+}
+
+func (e *QoSDowngradeError) Error() string {
+	return fmt.Sprintf("awsiotcore: broker granted QoS %d for subscription to %s, requested QoS %d", e.Granted, e.Filter, e.Requested)
+}
+
+// checkGrantedQoS returns a *QoSDowngradeError if the broker granted a lower QoS than requested for filter in t,
+// which must be a completed subscribe token for which checkSuback returned nil.
+func checkGrantedQoS(t mqtt.Token, filter string, requested byte) error {
+	st, ok := t.(interface{ Result() map[string]byte })
+	if !ok {
+		return nil
+	}
+
+	if granted, ok := st.Result()[subResultKey(filter)]; ok && granted < requested {
+		return &QoSDowngradeError{Filter: filter, Requested: requested, Granted: granted}
+	}
+	return nil
+}
+
+// subResultKey returns the key under which a subscribe token's Result reports filter. paho's Subscribe strips a
+// $share/<group>/ or $queue/ prefix from the filter before recording it.
+func subResultKey(filter string) string {
+	if strings.HasPrefix(filter, "$share/") {
+		if parts := strings.SplitN(filter, "/", 3); len(parts) == 3 {
+			filter = parts[2]
+		}
+	}
+	return strings.TrimPrefix(filter, "$queue/")
+}
+
+// checkSuback returns a *SubscriptionError if the broker rejected any of the subscriptions acknowledged in t, which
+// must be a completed subscribe token.
+func checkSuback(t mqtt.Token) error {
+	st, ok := t.(interface{ Result() map[string]byte })
+	if !ok {
+		return nil
+	}
+
+	result := st.Result()
+	filters := make([]string, 0, len(result))
+	for filter := range result {
+		filters = append(filters, filter)
+	}
+	sort.Strings(filters)
+
+	for _, filter := range filters {
+		if result[filter] == subackFailure {
+			return &SubscriptionError{Filter: filter}
+		}
+	}
+	return nil
+}
+
+// waitSubscribe waits for t, a subscribe token, to complete. It returns the token's error or, if the broker
+// rejected a subscription, a *SubscriptionError.
+func waitSubscribe(t mqtt.Token) error {
+	t.Wait()
+	if err := t.Error(); err != nil {
+		return err
+	}
+	return checkSuback(t)
+}
+
+// waitSubscribeTimeout is like waitSubscribe but waits at most timeout.
+func waitSubscribeTimeout(t mqtt.Token, timeout time.Duration) error {
+	if err := waitToken(t, timeout); err != nil {
+		return err
+	}
+	return checkSuback(t)
+}
+
+// Subscribe subscribes to filter and waits for the subscription to complete. Unlike the client's Subscribe, it
+// returns a *SubscriptionError if the broker rejects the subscription, and a *QoSDowngradeError if the broker grants
+// a lower QoS than qos.
+func Subscribe(client mqtt.Client, filter string, qos byte, handler mqtt.MessageHandler) error {
+	if err := checkQoS(qos); err != nil {
+		return err
+	}
+
+	t := client.Subscribe(filter, qos, handler)
+	if err := waitSubscribe(t); err != nil {
+		return fmt.Errorf("awsiotcore: failed to subscribe to %s: %w", filter, err)
+	}
+	return checkGrantedQoS(t, filter, qos)
+}
+
+// SharedSubscriptionFilter returns the topic filter for a shared subscription to topic by the given group, i.e.
+// $share/<group>/<topic>. The group name must be non-empty and must not contain "/", "+", or "#".
+func SharedSubscriptionFilter(group, topic string) (string, error) {
+	if group == "" || strings.ContainsAny(group, "/+#") {
+		return "", fmt.Errorf("awsiotcore: invalid shared subscription group %q", group)
+	}
+	if topic == "" {
+		return "", fmt.Errorf("awsiotcore: shared subscription topic must not be empty")
+	}
+
+	return fmt.Sprintf("$share/%s/%s", group, topic), nil
+}
+
+// SharedSubscribe makes a shared subscription to topic as a member of the given group and waits for it to complete.
+// The broker delivers each message on topic to only one of the group's subscribers, which balances message
+// processing across them. AWS IoT supports shared subscriptions over both MQTT 3.1.1 and MQTT 5. See
+// https://docs.aws.amazon.com/iot/latest/developerguide/mqtt.html#mqtt5-shared-subscription. Like Subscribe, it
+// returns a *SubscriptionError if the broker rejects the subscription, and a *QoSDowngradeError if the broker grants
+// a lower QoS than qos.
+func SharedSubscribe(client mqtt.Client, group, topic string, qos byte, handler mqtt.MessageHandler) error {
+	if err := checkQoS(qos); err != nil {
+		return err
+	}
+
+	filter, err := SharedSubscriptionFilter(group, topic)
+	if err != nil {
+		return err
+	}
+
+	t := client.Subscribe(filter, qos, handler)
+	if err := waitSubscribe(t); err != nil {
+		return fmt.Errorf("awsiotcore: failed to subscribe to %s: %w", filter, err)
+	}
+	return checkGrantedQoS(t, filter, qos)
+}
+
+// TopicParams returns the parts of topic matched by the wildcards in filter, in order. Each "+" matches one topic
+// level, and a trailing "#" matches all remaining levels, which are returned joined by "/". A shared subscription
+// prefix ($share/<group>/) on filter is ignored. It returns false if topic doesn't match filter.
+func TopicParams(filter, topic string) ([]string, bool) {
+	if strings.HasPrefix(filter, "$share/") {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return nil, false
+		}
+		filter = parts[2]
+	}
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	params := []string{}
+	for i, f := range filterLevels {
+		if f == "#" {
+			// "#" also matches the parent level, e.g. "a/#" matches "a".
+			if i == len(topicLevels) {
+				return append(params, ""), true
+			}
+			return append(params, strings.Join(topicLevels[i:], "/")), true
+		}
+
+		if i >= len(topicLevels) {
+			return nil, false
+		}
+
+		switch f {
+		case "+":
+			params = append(params, topicLevels[i])
+		case topicLevels[i]:
+		default:
+			return nil, false
+		}
+	}
+
+	if len(filterLevels) != len(topicLevels) {
+		return nil, false
+	}
+	return params, true
+}
+
+// SubscribeWithParams subscribes to filter and waits for the subscription to complete. For each message it calls
+// handler with the parts of the message's topic matched by the filter's wildcards (see TopicParams), which saves
+// each handler from parsing the topic. For example, with filter "things/+/telemetry" a message on
+// "things/foo/telemetry" is passed to handler with params ["foo"].
+func SubscribeWithParams(client mqtt.Client, filter string, qos byte, handler func(params []string, msg mqtt.Message)) error {
+	if err := checkQoS(qos); err != nil {
+		return err
+	}
+
+	h := func(c mqtt.Client, m mqtt.Message) {
+		params, ok := TopicParams(filter, m.Topic())
+		if !ok {
+			// paho only routes matching messages to the handler, so this can't happen in practice.
+			mqtt.ERROR.Printf("awsiotcore: topic %s does not match filter %s", m.Topic(), filter)
+			return
+		}
+		handler(params, m)
+	}
+
+	t := client.Subscribe(filter, qos, h)
+	if err := waitSubscribe(t); err != nil {
+		return fmt.Errorf("awsiotcore: failed to subscribe to %s: %w", filter, err)
+	}
+	return checkGrantedQoS(t, filter, qos)
+}
+
+// AWS IoT's limits on subscriptions. See https://docs.aws.amazon.com/general/latest/gr/iot-core.html.
+const (
+	maxSubscriptionsPerConnection = 50
+	maxTopicLen                   = 256
+	maxTopicSlashes               = 7
+)
+
+// ValidateTopicFilter returns an error if filter isn't a topic filter that AWS IoT accepts: it must be non-empty, at
+// most 256 bytes long, and have at most 7 slashes, "+" must occupy a whole topic level, and "#" must occupy the last
+// level. A shared subscription prefix ($share/<group>/) is allowed and not counted towards the limits.
+func ValidateTopicFilter(filter string) error {
+	f := filter
+	if strings.HasPrefix(f, "$share/") {
+		parts := strings.SplitN(f, "/", 3)
+		if len(parts) < 3 || parts[1] == "" || strings.ContainsAny(parts[1], "+#") {
+			return fmt.Errorf("awsiotcore: invalid shared subscription filter %q", filter)
+		}
+		f = parts[2]
+	}
+
+	if f == "" {
+		return fmt.Errorf("awsiotcore: topic filter must not be empty")
+	}
+	if len(f) > maxTopicLen {
+		return fmt.Errorf("awsiotcore: topic filter %q is %d bytes long; the maximum is %d", filter, len(f), maxTopicLen)
+	}
+	if n := strings.Count(f, "/"); n > maxTopicSlashes {
+		return fmt.Errorf("awsiotcore: topic filter %q has %d slashes; the maximum is %d", filter, n, maxTopicSlashes)
+	}
+
+	levels := strings.Split(f, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "+") && level != "+" {
+			return fmt.Errorf("awsiotcore: topic filter %q uses \"+\" within a level", filter)
+		}
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return fmt.Errorf("awsiotcore: topic filter %q uses \"#\" other than as the last level", filter)
+		}
+	}
+	return nil
+}
+
+// ValidateSubscriptionSet returns an error if a client couldn't subscribe to all of filters at once: if any isn't
+// valid (see ValidateTopicFilter), if any is repeated, or if there are more than the 50 subscriptions per
+// connection that AWS IoT allows by default. Beyond the limit AWS IoT rejects subscriptions, which otherwise is only
+// noticed when messages fail to arrive.
+func ValidateSubscriptionSet(filters []string) error {
+	if len(filters) > maxSubscriptionsPerConnection {
+		return fmt.Errorf("awsiotcore: %d subscriptions; AWS IoT allows %d per connection", len(filters), maxSubscriptionsPerConnection)
+	}
+
+	seen := make(map[string]bool, len(filters))
+	for _, f := range filters {
+		if err := ValidateTopicFilter(f); err != nil {
+			return err
+		}
+		if seen[f] {
+			return fmt.Errorf("awsiotcore: topic filter %q is repeated", f)
+		}
+		seen[f] = true
+	}
+	return nil
+}
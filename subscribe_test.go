@@ -0,0 +1,217 @@
+package awsiotcore
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestSubscribe(t *testing.T) {
+	client := newFakeClient()
+	if err := Subscribe(client, "things/foo/commands", 1, func(mqtt.Client, mqtt.Message) {}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := client.subs["things/foo/commands"]; !ok {
+		t.Errorf("not subscribed to things/foo/commands")
+	}
+
+	client.granted = map[string]byte{"things/bar/commands": subackFailure}
+	var subErr *SubscriptionError
+	if err := Subscribe(client, "things/bar/commands", 1, func(mqtt.Client, mqtt.Message) {}); !errors.As(err, &subErr) || subErr.Filter != "things/bar/commands" {
+		t.Errorf("got error %v, want a SubscriptionError for things/bar/commands", err)
+	}
+}
+
+func TestSubscribeQoSDowngrade(t *testing.T) {
+	client := newFakeClient()
+	client.granted = map[string]byte{"things/foo/commands": 0}
+
+	var downErr *QoSDowngradeError
+	err := Subscribe(client, "things/foo/commands", 1, func(mqtt.Client, mqtt.Message) {})
+	if !errors.As(err, &downErr) || downErr.Requested != 1 || downErr.Granted != 0 {
+		t.Errorf("got error %v, want a QoSDowngradeError from 1 to 0", err)
+	}
+	if _, ok := client.subs["things/foo/commands"]; !ok {
+		t.Errorf("not subscribed to things/foo/commands")
+	}
+
+	err = SubscribeWithParams(client, "things/foo/commands", 1, func([]string, mqtt.Message) {})
+	if !errors.As(err, &downErr) {
+		t.Errorf("SubscribeWithParams: got error %v, want a QoSDowngradeError", err)
+	}
+
+	if err := Subscribe(client, "things/foo/commands", 0, func(mqtt.Client, mqtt.Message) {}); err != nil {
+		t.Errorf("unexpected error for QoS 0 subscription granted QoS 0: %v", err)
+	}
+}
+
+func TestSharedSubscriptionFilter(t *testing.T) {
+	cases := []struct {
+		group   string
+		topic   string
+		want    string
+		wantErr bool
+	}{
+		{"workers", "things/+/telemetry", "$share/workers/things/+/telemetry", false},
+		{"", "things/+/telemetry", "", true},
+		{"a/b", "things/+/telemetry", "", true},
+		{"a+", "things/+/telemetry", "", true},
+		{"workers", "", "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			got, err := SharedSubscriptionFilter(c.group, c.topic)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("got nil error, want non-nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSharedSubscribe(t *testing.T) {
+	client := newFakeClient()
+	if err := SharedSubscribe(client, "workers", "things/+/telemetry", 1, func(mqtt.Client, mqtt.Message) {}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := client.subs["$share/workers/things/+/telemetry"]; !ok {
+		t.Errorf("not subscribed to shared subscription filter")
+	}
+}
+
+func TestSharedSubscribeQoSDowngrade(t *testing.T) {
+	client := newFakeClient()
+	// paho reports the granted QoS of a shared subscription under the filter without the $share/<group>/ prefix.
+	client.granted = map[string]byte{"things/+/telemetry": 0}
+
+	var downErr *QoSDowngradeError
+	err := SharedSubscribe(client, "workers", "things/+/telemetry", 1, func(mqtt.Client, mqtt.Message) {})
+	if !errors.As(err, &downErr) || downErr.Filter != "$share/workers/things/+/telemetry" {
+		t.Errorf("got error %v, want a QoSDowngradeError for $share/workers/things/+/telemetry", err)
+	}
+
+	err = Subscribe(client, "$share/workers/things/+/telemetry", 1, func(mqtt.Client, mqtt.Message) {})
+	if !errors.As(err, &downErr) {
+		t.Errorf("Subscribe: got error %v, want a QoSDowngradeError", err)
+	}
+}
+
+func TestSharedSubscribeQoS2(t *testing.T) {
+	client := newFakeClient()
+	err := SharedSubscribe(client, "workers", "things/+/telemetry", 2, func(mqtt.Client, mqtt.Message) {})
+	if !errors.Is(err, ErrUnsupportedQoS) {
+		t.Errorf("got error %v, want %v", err, ErrUnsupportedQoS)
+	}
+}
+
+func TestTopicParams(t *testing.T) {
+	cases := []struct {
+		filter string
+		topic  string
+		want   []string
+		ok     bool
+	}{
+		{"things/+/telemetry", "things/foo/telemetry", []string{"foo"}, true},
+		{"things/+/shadow/+", "things/foo/shadow/update", []string{"foo", "update"}, true},
+		{"things/foo/#", "things/foo/commands/reboot", []string{"commands/reboot"}, true},
+		{"things/foo/#", "things/foo", []string{""}, true},
+		{"things/+/#", "things/foo/a/b", []string{"foo", "a/b"}, true},
+		{"$share/workers/things/+/telemetry", "things/foo/telemetry", []string{"foo"}, true},
+		{"things/foo/telemetry", "things/foo/telemetry", []string{}, true},
+		{"things/+/telemetry", "things/foo/commands", nil, false},
+		{"things/+/telemetry", "things/foo/telemetry/extra", nil, false},
+		{"things/+/telemetry/extra", "things/foo/telemetry", nil, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.filter+" "+c.topic, func(t *testing.T) {
+			got, ok := TopicParams(c.filter, c.topic)
+			if ok != c.ok {
+				t.Fatalf("got ok %v, want %v", ok, c.ok)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeWithParams(t *testing.T) {
+	client := newFakeClient()
+	var got []string
+	err := SubscribeWithParams(client, "things/+/telemetry", 1, func(params []string, msg mqtt.Message) {
+		got = params
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The fake only routes exact matches, so deliver to the filter and give the message the concrete topic.
+	client.subs["things/+/telemetry"](client, &fakeMessage{topic: "things/foo/telemetry"})
+	if want := []string{"foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValidateTopicFilter(t *testing.T) {
+	cases := []struct {
+		filter  string
+		wantErr bool
+	}{
+		{"things/foo/telemetry", false},
+		{"things/+/telemetry", false},
+		{"things/#", false},
+		{"#", false},
+		{"$share/group/things/+/telemetry", false},
+		{"$aws/things/foo/shadow/name/bar/update/accepted", false},
+		{"", true},
+		{"$share/group/", true},
+		{"$share//things/foo", true},
+		{"things/fo+/telemetry", true},
+		{"things/#/telemetry", true},
+		{"things/foo#", true},
+		{"a/b/c/d/e/f/g/h/i", true},
+		{strings.Repeat("a", 257), true},
+	}
+
+	for _, c := range cases {
+		err := ValidateTopicFilter(c.filter)
+		if c.wantErr && err == nil {
+			t.Errorf("%q: got nil error, want non-nil", c.filter)
+		} else if !c.wantErr && err != nil {
+			t.Errorf("%q: unexpected error: %v", c.filter, err)
+		}
+	}
+}
+
+func TestValidateSubscriptionSet(t *testing.T) {
+	if err := ValidateSubscriptionSet([]string{"things/foo/commands", "$aws/things/foo/shadow/update/delta"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateSubscriptionSet([]string{"things/foo/commands", "things/foo/commands"}); err == nil {
+		t.Errorf("got nil error for repeated filter, want non-nil")
+	}
+
+	var many []string
+	for i := 0; i < 51; i++ {
+		many = append(many, fmt.Sprintf("things/foo/%d", i))
+	}
+	if err := ValidateSubscriptionSet(many); err == nil {
+		t.Errorf("got nil error for 51 filters, want non-nil")
+	}
+}
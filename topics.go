@@ -0,0 +1,95 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reservedThingsPrefix is the prefix of the reserved topics AWS IoT scopes to a single thing, e.g. shadow and jobs topics.
+// See https://docs.aws.amazon.com/iot/latest/developerguide/reserved-topics.html.
+const reservedThingsPrefix = "$aws/things/"
+
+// ThingNameFromTopic returns the thing name from a reserved topic of the form $aws/things/<name>/..., for example
+// one received on a subscription to $aws/things/+/shadow/update/delta.
+func ThingNameFromTopic(topic string) (string, error) {
+	if !strings.HasPrefix(topic, reservedThingsPrefix) {
+		return "", fmt.Errorf("awsiotcore: topic %q does not begin with %q", topic, reservedThingsPrefix)
+	}
+
+	name, rest, ok := strings.Cut(strings.TrimPrefix(topic, reservedThingsPrefix), "/")
+	if name == "" || !ok || rest == "" {
+		return "", fmt.Errorf("awsiotcore: topic %q is not of the form %s<name>/...", topic, reservedThingsPrefix)
+	}
+	return name, nil
+}
+
+// CheckPublishTopic returns an error if topic is a reserved $aws/things/<name>/... topic that belongs to a thing other
+// than this device. AWS IoT drops such publishes or disconnects the client without saying why, so it's worth checking
+// before publishing. If BridgeTopicPrefix is set then topic may be a local-broker topic.
+func (d *Device) CheckPublishTopic(topic string) error {
+	name, err := ThingNameFromTopic(d.AWSTopic(topic))
+	if err != nil {
+		return nil
+	}
+
+	if name != d.ID() {
+		return fmt.Errorf("awsiotcore: topic %q is reserved for thing %q, not %q", topic, name, d.ID())
+	}
+	return nil
+}
+
+// OwnsTopic reports whether topic belongs to this device, i.e. whether it's a reserved $aws/things/<name>/... topic
+// or one of the package's things/<name>/... topics, such as CommandTopic, whose thing name is the device's ID. It's a
+// cheap check in a message handler against messages misrouted by wildcard subscriptions or broker bridges. Topics of
+// any other form, including TelemetryTopicOverride, are not considered the device's. If BridgeTopicPrefix is set then
+// topic may be a local-broker topic.
+func (d *Device) OwnsTopic(topic string) bool {
+	topic = d.AWSTopic(topic)
+	if name, err := ThingNameFromTopic(topic); err == nil {
+		return name == d.ID()
+	}
+
+	rest, ok := strings.CutPrefix(topic, "things/")
+	if !ok {
+		return false
+	}
+	name, sub, ok := strings.Cut(rest, "/")
+	return ok && sub != "" && name == d.ID()
+}
+
+// bridgeTopic returns the local-broker topic for the given AWS IoT topic.
+func (d *Device) bridgeTopic(topic string) string {
+	return d.BridgeTopicPrefix + topic
+}
+
+// AWSTopic returns the AWS IoT topic that the given local-broker topic is bridged to, i.e. topic without
+// BridgeTopicPrefix. Topics without the prefix are returned unchanged.
+func (d *Device) AWSTopic(topic string) string {
+	return strings.TrimPrefix(topic, d.BridgeTopicPrefix)
+}
+
+// Topics returns the topics the device uses, keyed by name: the telemetry, config, command, lifecycle, heartbeat,
+// and offline topics, the classic shadow topics used by UpdateShadow and ShadowReconciler, and the jobs notify
+// topics and job executions filter. GeneratePolicy uses it to build a least-privilege policy from the device's config.
+// Stream topics aren't included since they depend on the stream ID.
+func (d *Device) Topics() map[string]string {
+	topics := map[string]string{
+		"telemetry":   d.TelemetryTopic(),
+		"config":      d.ConfigTopic(),
+		"commands":    d.CommandTopic(),
+		"command_ack": d.CommandAckTopic(),
+		"lifecycle":   d.LifecycleTopic(),
+		"heartbeat":   d.HeartbeatTopic(),
+		"offline":     d.OfflineTopic(),
+	}
+	for _, op := range []string{"get", "update"} {
+		topics["shadow_"+op] = d.ShadowTopic(op)
+		topics["shadow_"+op+"_accepted"] = d.ShadowTopic(op + "/accepted")
+		topics["shadow_"+op+"_rejected"] = d.ShadowTopic(op + "/rejected")
+	}
+	topics["shadow_update_delta"] = d.ShadowTopic("update/delta")
+	topics["jobs_notify"] = d.JobNotifyTopic()
+	topics["jobs_notify_next"] = d.JobNotifyNextTopic()
+	topics["job_executions"] = d.JobExecutionsFilter()
+	return topics
+}
@@ -0,0 +1,143 @@
+package awsiotcore
+
+import (
+	"testing"
+)
+
+func TestCheckPublishTopic(t *testing.T) {
+	device := Device{
+		Endpoint: "myendpoint",
+		DeviceID: "foo",
+	}
+
+	cases := []struct {
+		name    string
+		topic   string
+		wantErr bool
+	}{
+		{"telemetry", "things/foo/telemetry", false},
+		{"own_shadow", "$aws/things/foo/shadow/update", false},
+		{"other_shadow", "$aws/things/bar/shadow/update", true},
+		{"other_prefix", "$aws/things/foobar/shadow/update", true},
+		{"other_reserved", "$aws/events/presence/connected/foo", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := device.CheckPublishTopic(c.topic)
+			if c.wantErr && err == nil {
+				t.Errorf("got nil error, want non-nil")
+			} else if !c.wantErr && err != nil {
+				t.Errorf("got error %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestThingNameFromTopic(t *testing.T) {
+	cases := []struct {
+		topic   string
+		want    string
+		wantErr bool
+	}{
+		{"$aws/things/foo/shadow/update/delta", "foo", false},
+		{"$aws/things/foo:bar/jobs/notify", "foo:bar", false},
+		{"things/foo/telemetry", "", true},
+		{"$aws/things/", "", true},
+		{"$aws/things/foo", "", true},
+		{"$aws/things//shadow/update", "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.topic, func(t *testing.T) {
+			got, err := ThingNameFromTopic(c.topic)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("got nil error, want non-nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestBridgeTopicPrefix(t *testing.T) {
+	d := &Device{DeviceID: "foo", BridgeTopicPrefix: "aws/"}
+
+	cases := []struct {
+		got  string
+		want string
+	}{
+		{d.TelemetryTopic(), "aws/things/foo/telemetry"},
+		{d.ConfigTopic(), "aws/things/foo/config"},
+		{d.CommandAckTopic(), "aws/things/foo/commands/ack"},
+		{d.ShadowTopic("get"), "aws/$aws/things/foo/shadow/get"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("got %q, want %q", c.got, c.want)
+		}
+	}
+
+	if got := d.AWSTopic(d.ShadowTopic("get")); got != "$aws/things/foo/shadow/get" {
+		t.Errorf("got AWS topic %q, want %q", got, "$aws/things/foo/shadow/get")
+	}
+	if err := d.CheckPublishTopic("aws/$aws/things/bar/shadow/update"); err == nil {
+		t.Errorf("got nil error for another thing's bridged topic, want non-nil")
+	}
+}
+
+func TestTopics(t *testing.T) {
+	d := &Device{DeviceID: "foo", TelemetryTopicOverride: "fleet/telemetry"}
+	topics := d.Topics()
+
+	want := map[string]string{
+		"telemetry":              "fleet/telemetry",
+		"command_ack":            "things/foo/commands/ack",
+		"shadow_get_rejected":    "$aws/things/foo/shadow/get/rejected",
+		"shadow_update_delta":    "$aws/things/foo/shadow/update/delta",
+		"shadow_update_accepted": "$aws/things/foo/shadow/update/accepted",
+	}
+	for name, topic := range want {
+		if topics[name] != topic {
+			t.Errorf("%s: got %q, want %q", name, topics[name], topic)
+		}
+	}
+	if len(topics) != 17 {
+		t.Errorf("got %d topics, want 17: %v", len(topics), topics)
+	}
+}
+
+func TestOwnsTopic(t *testing.T) {
+	d := &Device{DeviceID: "foo", BridgeTopicPrefix: "aws/"}
+
+	cases := []struct {
+		topic string
+		want  bool
+	}{
+		{"aws/$aws/things/foo/shadow/update/delta", true},
+		{"$aws/things/foo/jobs/notify", true},
+		{"aws/things/foo/commands", true},
+		{"things/foo/commands/reboot", true},
+		{"aws/$aws/things/bar/shadow/update/delta", false},
+		{"things/bar/commands", false},
+		{"things/foobar/commands", false},
+		{"things/foo", false},
+		{"fleet/telemetry", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.topic, func(t *testing.T) {
+			if got := d.OwnsTopic(c.topic); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package awsiotcore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// PendingOperation describes a publish, subscribe, or unsubscribe that a TrackingClient is waiting on.
+type PendingOperation struct {
+	// Kind is "publish", "subscribe", or "unsubscribe".
+	Kind    string
+	Topics  []string
+	Started time.Time
+
+	token mqtt.Token
+}
+
+// TrackingClient wraps an mqtt.Client and keeps track of the publishes, subscribes, and unsubscribes whose tokens
+// haven't completed, which paho doesn't expose. This is useful at shutdown for logging what was lost or for waiting
+// for it to finish. It implements mqtt.Client, so it can be used anywhere the wrapped client was.
+//
+// paho has no way to cancel an operation. To abandon pending operations, call Disconnect, which completes their
+// tokens with errors.
+type TrackingClient struct {
+	mqtt.Client
+
+	mu      sync.Mutex
+	next    uint64
+	pending map[uint64]PendingOperation
+}
+
+// NewTrackingClient returns a TrackingClient that wraps client.
+func NewTrackingClient(client mqtt.Client) *TrackingClient {
+	return &TrackingClient{
+		Client:  client,
+		pending: make(map[uint64]PendingOperation),
+	}
+}
+
+// track records op as pending until its token completes.
+func (c *TrackingClient) track(t mqtt.Token, kind string, topics ...string) mqtt.Token {
+	c.mu.Lock()
+	id := c.next
+	c.next++
+	c.pending[id] = PendingOperation{Kind: kind, Topics: topics, Started: time.Now(), token: t}
+	c.mu.Unlock()
+
+	go func() {
+		<-t.Done()
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+	return t
+}
+
+func (c *TrackingClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	return c.track(c.Client.Publish(topic, qos, retained, payload), "publish", topic)
+}
+
+func (c *TrackingClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	return c.track(c.Client.Subscribe(topic, qos, callback), "subscribe", topic)
+}
+
+func (c *TrackingClient) SubscribeMultiple(filters map[string]byte, callback mqtt.MessageHandler) mqtt.Token {
+	topics := make([]string, 0, len(filters))
+	for topic := range filters {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return c.track(c.Client.SubscribeMultiple(filters, callback), "subscribe", topics...)
+}
+
+func (c *TrackingClient) Unsubscribe(topics ...string) mqtt.Token {
+	return c.track(c.Client.Unsubscribe(topics...), "unsubscribe", topics...)
+}
+
+// PendingOperations returns the number of operations whose tokens haven't completed.
+func (c *TrackingClient) PendingOperations() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// Pending returns the operations whose tokens haven't completed, oldest first.
+func (c *TrackingClient) Pending() []PendingOperation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ops := make([]PendingOperation, 0, len(c.pending))
+	for _, op := range c.pending {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].Started.Before(ops[j].Started)
+	})
+	return ops
+}
+
+// WaitPending waits for the operations that are pending when it's called to complete, or for ctx to be done,
+// whichever comes first. In the latter case it returns ctx.Err().
+func (c *TrackingClient) WaitPending(ctx context.Context) error {
+	for _, op := range c.Pending() {
+		select {
+		case <-op.token.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
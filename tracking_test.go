@@ -0,0 +1,37 @@
+package awsiotcore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackingClient(t *testing.T) {
+	fake := newFakeClient()
+	client := NewTrackingClient(fake)
+
+	if err := PublishBytes(client, "things/foo/telemetry", 1, false, []byte("x")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for client.PendingOperations() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := client.PendingOperations(); n != 0 {
+		t.Errorf("got %d pending operations after publish completed, want 0", n)
+	}
+
+	fake.publishHangs = true
+	client.Publish("things/foo/telemetry", 1, false, []byte("y"))
+	pending := client.Pending()
+	if len(pending) != 1 || pending[0].Kind != "publish" || pending[0].Topics[0] != "things/foo/telemetry" {
+		t.Errorf("got pending %+v, want one publish to things/foo/telemetry", pending)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.WaitPending(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
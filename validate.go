@@ -0,0 +1,89 @@
+package awsiotcore
+
+import (
+	"fmt"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Validate returns an error if any of the fields required to connect are not set. CertPath and PrivKeyPath are
+// required unless CustomAuthorizer is set, and either both or neither must be set. Every QoS in TopicQoS must be
+// supported by AWS IoT.
+func (d *Device) Validate() error {
+	type field struct {
+		name  string
+		value string
+	}
+
+	required := []field{
+		{"Endpoint", d.Endpoint},
+		{"DeviceID", d.DeviceID},
+		{"CACerts", d.CACerts},
+	}
+	if d.CustomAuthorizer == "" {
+		required = append(required, field{"CertPath", d.CertPath}, field{"PrivKeyPath", d.PrivKeyPath})
+	}
+
+	for _, r := range required {
+		if r.value == "" {
+			return fmt.Errorf("awsiotcore: %s must be set", r.name)
+		}
+	}
+
+	if (d.CertPath == "") != (d.PrivKeyPath == "") {
+		return fmt.Errorf("awsiotcore: CertPath and PrivKeyPath must both be set or both be empty")
+	}
+
+	for filter, qos := range d.TopicQoS {
+		if err := checkQoS(qos); err != nil {
+			return fmt.Errorf("awsiotcore: TopicQoS for %s: %w", filter, err)
+		}
+	}
+
+	return ValidateDeviceID(d.DeviceID)
+}
+
+// maxThingNameLen is the maximum length of an AWS IoT thing name.
+const maxThingNameLen = 128
+
+// ValidateDeviceID returns an error if id is not a valid AWS IoT thing name, which may contain only the characters
+// a-z, A-Z, 0-9, ':', '_', and '-', and must be 1 to 128 characters long.
+func ValidateDeviceID(id string) error {
+	if id == "" {
+		return fmt.Errorf("awsiotcore: device ID must not be empty")
+	}
+	if len(id) > maxThingNameLen {
+		return fmt.Errorf("awsiotcore: device ID is %d characters long; the maximum is %d", len(id), maxThingNameLen)
+	}
+
+	for i, r := range id {
+		if !isThingNameChar(r) {
+			return fmt.Errorf("awsiotcore: device ID %q contains %q at index %d; only a-z, A-Z, 0-9, ':', '_', and '-' are allowed", id, r, i)
+		}
+	}
+
+	return nil
+}
+
+func isThingNameChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == ':' || r == '_' || r == '-'
+}
+
+// isLegacyEndpoint reports whether endpoint is an AWS IoT endpoint that is not an Amazon Trust Services (ATS)
+// endpoint. Endpoints that aren't under amazonaws.com, like custom domains and non-AWS brokers, are not considered legacy.
+func isLegacyEndpoint(endpoint string) bool {
+	return strings.HasSuffix(endpoint, ".amazonaws.com") && !strings.Contains(endpoint, "-ats.")
+}
+
+// warnLegacyEndpoint logs a warning to paho's WARN logger if the device's endpoint is a legacy endpoint, unless the
+// device has LegacyEndpoint set. Legacy endpoints present certs signed by a Symantec root, so they fail to verify
+// against the Amazon Trust Services roots that the README tells users to download.
+func (d *Device) warnLegacyEndpoint() {
+	if d.LegacyEndpoint || !isLegacyEndpoint(d.Endpoint) {
+		return
+	}
+
+	mqtt.WARN.Printf("awsiotcore: endpoint %s is not an ATS endpoint; TLS verification will fail unless CACerts "+
+		"contains the legacy Symantec root. Set LegacyEndpoint to silence this warning.", d.Endpoint)
+}
@@ -0,0 +1,92 @@
+package awsiotcore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Device{
+		Endpoint:    "abc123-ats.iot.us-west-2.amazonaws.com",
+		DeviceID:    "foo",
+		CACerts:     "roots.pem",
+		CertPath:    "foo.x509",
+		PrivKeyPath: "foo.pem",
+	}
+
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error for valid device: %v", err)
+	}
+
+	missing := valid
+	missing.CertPath = ""
+	if err := missing.Validate(); err == nil {
+		t.Errorf("got nil error for device with no CertPath, want non-nil")
+	}
+
+	customAuth := valid
+	customAuth.CertPath = ""
+	customAuth.PrivKeyPath = ""
+	customAuth.CustomAuthorizer = "my-authorizer"
+	if err := customAuth.Validate(); err != nil {
+		t.Errorf("unexpected error for device with custom authorizer and no cert: %v", err)
+	}
+
+	customAuth.CertPath = "foo.x509"
+	if err := customAuth.Validate(); err == nil {
+		t.Errorf("got nil error for device with CertPath but no PrivKeyPath, want non-nil")
+	}
+
+	qos2 := valid
+	qos2.TopicQoS = map[string]byte{"things/foo/telemetry": 2}
+	if err := qos2.Validate(); err == nil {
+		t.Errorf("got nil error for device with QoS 2 in TopicQoS, want non-nil")
+	}
+}
+
+func TestIsLegacyEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		want     bool
+	}{
+		{"abc123-ats.iot.us-west-2.amazonaws.com", false},
+		{"abc123.iot.us-west-2.amazonaws.com", true},
+		{"iot.example.com", false},
+		{"localhost", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.endpoint, func(t *testing.T) {
+			if got := isLegacyEndpoint(c.endpoint); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestValidateDeviceID(t *testing.T) {
+	cases := []struct {
+		id      string
+		wantErr bool
+	}{
+		{"foo", false},
+		{"my-device_01:a", false},
+		{strings.Repeat("a", 128), false},
+		{"", true},
+		{strings.Repeat("a", 129), true},
+		{"my device", true},
+		{"foo/bar", true},
+		{"café", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.id, func(t *testing.T) {
+			err := ValidateDeviceID(c.id)
+			if c.wantErr && err == nil {
+				t.Errorf("got nil error, want non-nil")
+			} else if !c.wantErr && err != nil {
+				t.Errorf("got error %v, want nil", err)
+			}
+		})
+	}
+}